@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+)
+
+type ArchivalOptions struct{}
+
+type ArchivalInitOptions struct {
+	Force bool `long:"force" description:"Replace an existing archival file with other contents"`
+}
+
+func executeArchivalInit(options ParserOptions, opts ArchivalInitOptions) error {
+	_, workTree, err := internal.FindRepository(options.Dir)
+	if err != nil {
+		return fmt.Errorf("unable to open git repository: %v", err)
+	}
+	if workTree == "" {
+		return fmt.Errorf("bare repositories have no worktree to write %s to", internal.ArchivalFileName)
+	}
+	return internal.WriteArchivalFiles(workTree, opts.Force)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+	"github.com/choffmeister/git-describe-semver/pkg/describe"
+)
+
+type GenerateOptions struct {
+	Template string `long:"template" description:"Template file to render instead of the built-in template for the file extension"`
+	Package  string `long:"package" description:"Package of version.go (defaults to the name of its directory)"`
+	Args     struct {
+		Files []string `positional-arg-name:"file" description:"The files to write (version.go, version.h, _version.py, version.rs, version.json or any file with --template), relative to the worktree directory" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func executeGenerate(options ParserOptions, opts GenerateOptions, out io.Writer) error {
+	text := ""
+	if opts.Template != "" {
+		path := opts.Template
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(options.Dir, path)
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read template: %v", err)
+		}
+		text = string(contents)
+	}
+	describeOpts := describeOptions(options)
+	describeOpts.DetectDirty = true
+	result, err := describe.Describe(context.Background(), describeOpts)
+	if err != nil {
+		return err
+	}
+	prefix := options.PrereleasePrefix
+	if prefix == "" {
+		prefix = "dev"
+	}
+	data := internal.NewVersionFileData(describe.InternalVersion(result.Version), result.Commit, result.Distance, result.Dirty, prefix)
+	data.Package = opts.Package
+	for _, file := range opts.Args.Files {
+		path := filepath.Join(options.Dir, file)
+		contents, err := internal.RenderVersionFile(path, text, data)
+		if err != nil {
+			return err
+		}
+		written, err := internal.WriteVersionFile(path, contents)
+		if err != nil {
+			return err
+		}
+		if written {
+			fmt.Fprintf(out, "%s: updated\n", file)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteGenerate(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	defer os.RemoveAll(dir)
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "file.txt"), []byte("first"), 0o644))
+	worktree.Add("file.txt")
+	hash, err := worktree.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@test.com", When: time.Now()},
+	})
+	assert.NoError(err)
+	repo.CreateTag("v2.0.0", hash, nil)
+	options := ParserOptions{Dir: dir, Backend: "auto", Base: "nearest", PrereleasePrefix: "dev", OnCollision: "ignore"}
+
+	output := &bytes.Buffer{}
+	opts := GenerateOptions{}
+	opts.Args.Files = []string{"version/version.go", "version.json"}
+	assert.NoError(executeGenerate(options, opts, output))
+	assert.Equal("version/version.go: updated\nversion.json: updated\n", output.String())
+	contents, err := os.ReadFile(filepath.Join(dir, "version/version.go"))
+	assert.NoError(err)
+	assert.Contains(string(contents), "package version\n")
+	assert.Contains(string(contents), "Commit        = \""+hash.String()+"\"\n")
+
+	output.Reset()
+	assert.NoError(executeGenerate(options, opts, output))
+	assert.Equal("", output.String())
+
+	assert.NoError(os.WriteFile(filepath.Join(dir, "version.tmpl"), []byte("{{ .Version }} {{ .Dirty }}\n"), 0o644))
+	opts = GenerateOptions{Template: "version.tmpl"}
+	opts.Args.Files = []string{"VERSION"}
+	assert.NoError(executeGenerate(options, opts, output))
+	contents, err = os.ReadFile(filepath.Join(dir, "VERSION"))
+	assert.NoError(err)
+	assert.Equal("v2.0.0 false\n", string(contents))
+
+	opts.Template = "missing.tmpl"
+	assert.Error(executeGenerate(options, opts, output))
+}
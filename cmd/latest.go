@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+)
+
+type LatestOptions struct {
+	Constraint string `long:"constraint" description:"Only consider tags satisfying this constraint (like \">=1.2.0 <2.0.0\" or \"^1.4\")"`
+}
+
+func executeLatest(options ParserOptions, opts LatestOptions) (string, error) {
+	var constraint *internal.Constraint
+	if opts.Constraint != "" {
+		c, err := internal.ParseConstraint(opts.Constraint)
+		if err != nil {
+			return "", err
+		}
+		constraint = c
+	}
+	repo, err := internal.OpenRepository(options.Dir, options.Backend)
+	if err != nil {
+		return "", fmt.Errorf("unable to open git repository: %v", err)
+	}
+	describeOpts := internal.GitDescribeOptions{Loose: options.Loose}
+	if options.AsOf != "" {
+		asOf, err := internal.GitParseAsOf(repo, options.AsOf)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse as-of: %v", err)
+		}
+		describeOpts.AsOf = *asOf
+	}
+	tags := internal.GitTagsCached
+	if options.NoCache {
+		tags = internal.GitTags
+	}
+	tagMap, err := tags(repo, describeOpts)
+	if err != nil {
+		return "", fmt.Errorf("unable to get tags: %v", err)
+	}
+	tagNames := []string{}
+	for tagName := range *tagMap {
+		tagNames = append(tagNames, tagName)
+	}
+	result := internal.HighestTag(tagNames, constraint, options.Loose)
+	if result == "" {
+		if constraint != nil {
+			return "", fmt.Errorf("no tag satisfies %s", constraint)
+		}
+		return "", fmt.Errorf("no tags found")
+	}
+	return result, nil
+}
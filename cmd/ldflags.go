@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/choffmeister/git-describe-semver/pkg/describe"
+)
+
+type LdflagsOptions struct {
+	VersionVar   string `long:"version-var" default:"main.version" description:"Variable to stamp the version into (empty omits it)"`
+	CommitVar    string `long:"commit-var" default:"main.commit" description:"Variable to stamp the full commit hash into (empty omits it)"`
+	DateVar      string `long:"date-var" default:"main.date" description:"Variable to stamp the commit date (RFC 3339, UTC) into (empty omits it)"`
+	TreeStateVar string `long:"tree-state-var" description:"Variable to stamp the tree state (clean or dirty) into (empty omits it)"`
+	Bare         bool   `long:"bare" description:"Print only the -X flags, to be used within -ldflags"`
+}
+
+func executeLdflags(options ParserOptions, opts LdflagsOptions) (string, error) {
+	describeOpts := describeOptions(options)
+	describeOpts.DetectDirty = opts.TreeStateVar != ""
+	result, err := describe.Describe(context.Background(), describeOpts)
+	if err != nil {
+		return "", err
+	}
+	treeState := "clean"
+	if result.Dirty {
+		treeState = "dirty"
+	}
+	flags := []string{}
+	for _, v := range [][2]string{
+		{opts.VersionVar, result.Version.String()},
+		{opts.CommitVar, result.Commit},
+		{opts.DateVar, result.CommitTime.UTC().Format(time.RFC3339)},
+		{opts.TreeStateVar, treeState},
+	} {
+		if v[0] != "" {
+			flags = append(flags, fmt.Sprintf("-X %s=%s", v[0], v[1]))
+		}
+	}
+	if len(flags) == 0 {
+		return "", fmt.Errorf("no variable to stamp")
+	}
+	if opts.Bare {
+		return strings.Join(flags, " "), nil
+	}
+	return fmt.Sprintf("-ldflags \"%s\"", strings.Join(flags, " ")), nil
+}
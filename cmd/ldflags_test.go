@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteLdflags(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	defer os.RemoveAll(dir)
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "file.txt"), []byte("first"), 0o644))
+	worktree.Add("file.txt")
+	hash, err := worktree.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@test.com", When: time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))},
+	})
+	assert.NoError(err)
+	repo.CreateTag("v2.0.0", hash, nil)
+	options := ParserOptions{Dir: dir, Backend: "auto", Base: "nearest", PrereleasePrefix: "dev", OnCollision: "ignore"}
+	opts := LdflagsOptions{VersionVar: "main.version", CommitVar: "main.commit", DateVar: "main.date"}
+
+	output, err := executeLdflags(options, opts)
+	assert.NoError(err)
+	assert.Equal("-ldflags \"-X main.version=v2.0.0 -X main.commit="+hash.String()+" -X main.date=2024-05-01T10:00:00Z\"", output)
+
+	assert.NoError(os.WriteFile(filepath.Join(dir, "file.txt"), []byte("changed"), 0o644))
+	output, err = executeLdflags(options, LdflagsOptions{VersionVar: "example.com/app/version.Version", TreeStateVar: "example.com/app/version.TreeState", Bare: true})
+	assert.NoError(err)
+	assert.Equal("-X example.com/app/version.Version=v2.0.0 -X example.com/app/version.TreeState=dirty", output)
+
+	_, err = executeLdflags(options, LdflagsOptions{})
+	assert.EqualError(err, "no variable to stamp")
+}
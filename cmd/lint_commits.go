@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+)
+
+type LintCommitsOptions struct {
+	Types           string `long:"types" description:"Comma separated list of allowed commit types (defaults to build, chore, ci, docs, feat, fix, perf, refactor, revert, style and test)"`
+	Scopes          string `long:"scopes" description:"Comma separated list of allowed scopes (any if empty)"`
+	MaxHeaderLength int    `long:"max-header-length" default:"100" description:"Maximum length of the commit header (0 for unlimited)"`
+	Output          string `long:"output" default:"text" description:"Format of the report" choice:"text" choice:"json"`
+	Args            struct {
+		Range string `positional-arg-name:"range" description:"The commits to lint as <from>..<to> (defaults to the commits since the last semver tag)"`
+	} `positional-args:"yes"`
+}
+
+func executeLintCommits(options ParserOptions, opts LintCommitsOptions, output io.Writer) error {
+	lintOpts := internal.LintOptions{
+		Types:           internal.DefaultConventionalTypes,
+		Scopes:          splitList(opts.Scopes),
+		MaxHeaderLength: opts.MaxHeaderLength,
+	}
+	if opts.Types != "" {
+		lintOpts.Types = splitList(opts.Types)
+	}
+	repo, err := internal.OpenRepository(options.Dir, options.Backend)
+	if err != nil {
+		return fmt.Errorf("unable to open git repository: %v", err)
+	}
+	from, to, err := lintCommitsRange(repo, options, opts.Args.Range)
+	if err != nil {
+		return err
+	}
+	results, err := internal.GitLintCommits(repo, from, to, lintOpts)
+	if err != nil {
+		return err
+	}
+	violating := 0
+	for _, result := range results {
+		if len(result.Violations) > 0 {
+			violating++
+		}
+	}
+	if opts.Output == "json" {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			for _, violation := range result.Violations {
+				fmt.Fprintf(output, "%s %s: %s\n", result.Commit[0:7], result.Header, violation)
+			}
+		}
+	}
+	if violating > 0 {
+		return fmt.Errorf("%d of %d commits violate the Conventional Commits rules", violating, len(results))
+	}
+	return nil
+}
+
+// lintCommitsRange resolves <from>..<to> (with <to> defaulting to HEAD) or,
+// if empty, the commits since the tag or version note the version is based on
+func lintCommitsRange(repo internal.Repository, options ParserOptions, revRange string) (string, string, error) {
+	from, toRev := "", "HEAD"
+	if revRange != "" {
+		fromRev, rest, found := strings.Cut(revRange, "..")
+		if !found || strings.HasPrefix(rest, ".") {
+			return "", "", fmt.Errorf("invalid range %s, expected <from>..<to>", revRange)
+		}
+		if rest != "" {
+			toRev = rest
+		}
+		if fromRev != "" {
+			hash, err := repo.ResolveRevision(fromRev)
+			if err != nil {
+				return "", "", fmt.Errorf("unable to resolve %s: %v", fromRev, err)
+			}
+			from = hash
+		}
+	} else {
+		describeOpts := internal.GitDescribeOptions{
+			Base:         options.Base,
+			AllowShallow: true,
+			AllowNoTags:  true,
+			Loose:        options.Loose,
+			NotesRef:     options.NotesRef,
+		}
+		if options.AsOf != "" {
+			asOf, err := internal.GitParseAsOf(repo, options.AsOf)
+			if err != nil {
+				return "", "", fmt.Errorf("unable to parse as-of: %v", err)
+			}
+			describeOpts.AsOf = *asOf
+		}
+		tagName, _, _, err := internal.GitDescribe(repo, describeOpts)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to describe commit: %v", err)
+		}
+		if *tagName != "" {
+			if from, err = internal.GitResolveBase(repo, *tagName, describeOpts); err != nil {
+				return "", "", err
+			}
+		}
+	}
+	to, err := repo.ResolveRevision(toRev)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to resolve %s: %v", toRev, err)
+	}
+	return from, to, nil
+}
+
+func splitList(str string) []string {
+	result := []string{}
+	for _, item := range strings.Split(str, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteLintCommits(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	defer os.RemoveAll(dir)
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	commit := func(message string) string {
+		hash, err := worktree.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test", Email: "test@test.com", When: time.Now()},
+		})
+		assert.NoError(err)
+		return hash.String()
+	}
+	commit1 := commit("initial")
+	head, _ := repo.Head()
+	repo.CreateTag("v1.0.0", head.Hash(), nil)
+	commit("feat: first")
+	commit3 := commit("second")
+	options := ParserOptions{Dir: dir, Backend: "auto", Base: "nearest"}
+
+	output := &bytes.Buffer{}
+	err := executeLintCommits(options, LintCommitsOptions{Output: "text"}, output)
+	assert.EqualError(err, "1 of 2 commits violate the Conventional Commits rules")
+	assert.Equal(commit3[0:7]+" second: header does not match \"<type>[(<scope>)][!]: <description>\"\n", output.String())
+
+	output.Reset()
+	opts := LintCommitsOptions{Types: "feat", Output: "json"}
+	opts.Args.Range = commit1 + ".."
+	assert.Error(executeLintCommits(options, opts, output))
+	results := []internal.LintResult{}
+	assert.NoError(json.Unmarshal(output.Bytes(), &results))
+	assert.Len(results, 2)
+
+	opts.Args.Range = commit3
+	assert.EqualError(executeLintCommits(options, opts, output), "invalid range "+commit3+", expected <from>..<to>")
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+)
+
+type NoteOptions struct {
+	Force bool `long:"force" description:"Replace another version already noted on the commit and allow noting a version already noted on another commit"`
+	Args  struct {
+		Version string `positional-arg-name:"version" description:"The version to note" required:"yes"`
+		Commit  string `positional-arg-name:"commit" description:"The commit to note the version on (defaults to HEAD)"`
+	} `positional-args:"yes"`
+}
+
+func executeNote(options ParserOptions, opts NoteOptions) error {
+	if options.NotesRef == "" {
+		return fmt.Errorf("the notes ref is required")
+	}
+	repo, err := internal.OpenRepository(options.Dir, options.Backend)
+	if err != nil {
+		return fmt.Errorf("unable to open git repository: %v", err)
+	}
+	rev := opts.Args.Commit
+	if rev == "" {
+		rev = "HEAD"
+	}
+	commit, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return fmt.Errorf("unable to resolve %s: %v", rev, err)
+	}
+	return internal.GitSetVersionNote(repo, opts.Args.Version, commit, opts.Force, internal.GitDescribeOptions{
+		NotesRef: options.NotesRef,
+		Loose:    options.Loose,
+	})
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+)
+
+type PromoteOptions struct {
+	Message string `short:"m" long:"message" description:"Message of the release tag (defaults to the message of the prerelease tag)"`
+	DryRun  bool   `long:"dry-run" description:"Only print the release tag without creating it"`
+	Args    struct {
+		Tag string `positional-arg-name:"tag" description:"The prerelease tag to promote (defaults to the highest prerelease tag on HEAD)"`
+	} `positional-args:"yes"`
+}
+
+func executePromote(options ParserOptions, opts PromoteOptions) (string, error) {
+	repo, err := internal.OpenRepository(options.Dir, options.Backend)
+	if err != nil {
+		return "", fmt.Errorf("unable to open git repository: %v", err)
+	}
+	result, err := internal.PromoteTag(repo, opts.Args.Tag, internal.PromoteOptions{
+		Message: opts.Message,
+		Loose:   options.Loose,
+		DryRun:  opts.DryRun,
+	})
+	if err != nil {
+		return "", err
+	}
+	return *result, nil
+}
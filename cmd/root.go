@@ -1,31 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"runtime/debug"
 	"strings"
-	"time"
 
-	"github.com/choffmeister/git-describe-semver/internal"
+	"github.com/choffmeister/git-describe-semver/pkg/describe"
 	"github.com/jessevdk/go-flags"
 )
 
-func run(dir string, opts internal.GenerateVersionOptions) (*string, error) {
-	repo, err := internal.OpenRepository(dir)
-	if err != nil {
-		return nil, fmt.Errorf("unable to open git repository: %v", err)
-	}
-	tagName, counter, headHash, err := internal.GitDescribe(*repo)
-	if err != nil {
-		return nil, fmt.Errorf("unable to describe commit: %v", err)
-	}
-	result, err := internal.GenerateVersion(*tagName, *counter, *headHash, time.Now(), opts)
-	if err != nil {
-		return nil, fmt.Errorf("unable to generate version: %v", err)
-	}
-	return result, nil
+func warn(msg string) {
+	fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
 }
 
 func openStdoutOrFile(file string) (io.WriteCloser, error) {
@@ -36,19 +24,59 @@ func openStdoutOrFile(file string) (io.WriteCloser, error) {
 }
 
 type ParserOptions struct {
-	Dir                   string `long:"dir" default:"." description:"The git worktree directory"`
-	Fallback              string `long:"fallback" description:"The first version to fallback to should there be no tag"`
-	DropPrefix            bool   `long:"drop-prefix" description:"Drop prefix from output"`
-	PrereleaseSuffix      string `long:"prerelease-suffix" description:"Suffix to add to prereleases"`
-	PrereleasePrefix      string `long:"prerelease-prefix" default:"dev" description:"Prefix to use as start of prerelease"`
-	PrereleaseTimestamped bool   `long:"prerelease-timestamped" description:"Use timestamp instead of commit count for prerelease"`
-	NextRelease           string `long:"next-release" description:"Bump current version to next release" choice:"major" choice:"minor" choice:"patch"`
-	Format                string `long:"format" description:"Format of output (use <version> as placeholder)"`
+	Dir                   string   `long:"dir" default:"." description:"The git worktree directory"`
+	NoCache               bool     `long:"no-cache" description:"Do not use or update the describe cache in the git directory"`
+	Backend               string   `long:"backend" default:"auto" description:"How to access the git repository" choice:"auto" choice:"go-git" choice:"git"`
+	Fallback              string   `long:"fallback" description:"The first version to fallback to should there be no tag"`
+	FallbackFrom          string   `long:"fallback-from" description:"Read the fallback from a manifest (VERSION, package.json, Chart.yaml, Cargo.toml, pyproject.toml or pom.xml)"`
+	FallbackIfHigher      bool     `long:"fallback-if-higher" description:"Also use the fallback if it is higher than the tag"`
+	Base                  string   `long:"base" default:"nearest" description:"Which tag to use as base version" choice:"nearest" choice:"highest"`
+	AllowShallow          bool     `long:"allow-shallow" description:"Fall back instead of failing when the repository is a shallow clone"`
+	AllowNoTags           bool     `long:"allow-no-tags" description:"Fall back instead of failing when the repository has no tags at all"`
+	AsOf                  string   `long:"as-of" description:"Ignore tags created after this point in time (date for its end in UTC, @unix-timestamp or commit-time)"`
+	DropPrefix            bool     `long:"drop-prefix" description:"Drop prefix from output"`
+	PrereleaseSuffix      string   `long:"prerelease-suffix" description:"Suffix to add to prereleases"`
+	PrereleasePrefix      string   `long:"prerelease-prefix" default:"dev" description:"Prefix to use as start of prerelease"`
+	PrereleaseTimestamped bool     `long:"prerelease-timestamped" description:"Use timestamp instead of commit count for prerelease"`
+	TimestampSource       string   `long:"timestamp-source" default:"now" description:"Source of the timestamp for timestamped prereleases" choice:"now" choice:"commit" choice:"author" choice:"tag" choice:"SOURCE_DATE_EPOCH"`
+	TimestampLayout       string   `long:"timestamp-layout" default:"unix" description:"Layout of the timestamp for timestamped prereleases" choice:"unix" choice:"YYYYMMDDHHMMSS" choice:"YYYYMMDD.N"`
+	NextRelease           string   `long:"next-release" description:"Bump current version to next release" choice:"major" choice:"minor" choice:"patch"`
+	NextPrerelease        string   `long:"next-prerelease" description:"Compute the next prerelease tag of this channel (like alpha, beta or rc) for the next release"`
+	Format                string   `long:"format" description:"Format of output (use <version> as placeholder)"`
+	Abbrev                *int     `long:"abbrev" description:"Minimum number of hash characters (0 omits the hash, defaults to the shortest unique prefix with at least 7 characters)"`
+	Loose                 bool     `long:"loose" description:"Coerce legacy tags like v1.2, release-3 or 1.2.3.4 into semver"`
+	Verbose               bool     `short:"v" long:"verbose" description:"Print additional information (like applied coercions) to stderr"`
+	ExcludeMessage        []string `long:"exclude-message" description:"Regular expression matching messages of commits that do not count towards the version (can be repeated)"`
+	NotesRef              string   `long:"notes-ref" default:"refs/notes/versions" description:"Notes ref with version notes overriding the version of HEAD or serving as base version (empty disables them)"`
+	OnCollision           string   `long:"on-collision" default:"ignore" description:"What to do should the version collide with an existing tag" choice:"ignore" choice:"warn" choice:"fail" choice:"advance"`
 }
 
 func Execute(version FullVersion) error {
 	var options ParserOptions
+	var satisfiesOptions SatisfiesOptions
+	var latestOptions LatestOptions
+	var promoteOptions PromoteOptions
+	var verifyTagOptions VerifyTagOptions
+	var lintCommitsOptions LintCommitsOptions
+	var noteOptions NoteOptions
+	var archivalOptions ArchivalOptions
+	var archivalInitOptions ArchivalInitOptions
+	var syncOptions SyncOptions
+	var generateOptions GenerateOptions
+	var ldflagsOptions LdflagsOptions
 	parser := flags.NewParser(&options, flags.Default)
+	parser.SubcommandsOptional = true
+	parser.AddCommand("satisfies", "Check whether a version satisfies a constraint", "Exits with a non-zero code if the version does not satisfy the constraint.", &satisfiesOptions)
+	parser.AddCommand("latest", "Print the highest tag", "Print the highest semver tag of the repository, optionally limited to tags satisfying a constraint.", &latestOptions)
+	parser.AddCommand("promote", "Promote a prerelease tag to a release", "Tag the commit of a tested prerelease (like v2.1.0-rc.3) with the release version (like v2.1.0) and print the release tag.", &promoteOptions)
+	parser.AddCommand("verify-tag", "Verify a proposed version tag", "Check that the tag is valid semver, higher than every ancestor tag and does not skip versions. Can be installed as pre-push hook.", &verifyTagOptions)
+	parser.AddCommand("lint-commits", "Lint commit messages against Conventional Commits", "Check the commits since the last semver tag (or of the given range) against the Conventional Commits rules and report the violations.", &lintCommitsOptions)
+	parser.AddCommand("note", "Note a version on a commit", "Store a version note on the commit (defaults to HEAD), which overrides its version and serves as base version of its descendants without moving tags.", &noteOptions)
+	archival, _ := parser.AddCommand("archival", "Support source archives created by git archive", "Source archives created by git archive contain no repository. Their version is computed from the .git_archival.txt file substituted by git archive instead.", &archivalOptions)
+	archival.AddCommand("init", "Write the archival file", "Write .git_archival.txt and enable its substitution in .gitattributes.", &archivalInitOptions)
+	parser.AddCommand("sync", "Write the version into project manifests", "Update the version of the manifests (defaults to the ones present in the worktree directory) in the flavor of their ecosystem, preserving their formatting.", &syncOptions)
+	parser.AddCommand("generate", "Write version source files", "Write source files with the version, its parts, the commit hash, the distance and the dirty flag (version.go, version.h, _version.py, version.rs, version.json or from a template). Files are only rewritten when their contents change.", &generateOptions)
+	parser.AddCommand("ldflags", "Print Go linker flags stamping the version", "Print -ldflags with -X flags setting the version, the full commit hash, the commit date and the tree state (clean or dirty) of a single describe run into the given variables.", &ldflagsOptions)
 	args, err := parser.Parse()
 	if err != nil {
 		switch flagsErr := err.(type) {
@@ -62,20 +90,43 @@ func Execute(version FullVersion) error {
 		}
 	}
 
-	opts := internal.GenerateVersionOptions{
-		FallbackTagName:       options.Fallback,
-		DropTagNamePrefix:     options.DropPrefix,
-		PrereleaseSuffix:      options.PrereleaseSuffix,
-		PrereleasePrefix:      options.PrereleasePrefix,
-		PrereleaseTimestamped: options.PrereleaseTimestamped,
-		NextRelease:           options.NextRelease,
-		Format:                options.Format,
+	var result string
+	if parser.Active == nil {
+		result, err = executeDescribe(options)
+	} else {
+		switch parser.Active.Name {
+		case "satisfies":
+			return executeSatisfies(satisfiesOptions)
+		case "latest":
+			result, err = executeLatest(options, latestOptions)
+		case "verify-tag":
+			return executeVerifyTag(options, verifyTagOptions)
+		case "archival":
+			return executeArchivalInit(options, archivalInitOptions)
+		case "sync":
+			return executeSync(options, syncOptions, os.Stdout)
+		case "generate":
+			return executeGenerate(options, generateOptions, os.Stdout)
+		case "note":
+			return executeNote(options, noteOptions)
+		case "lint-commits":
+			return executeLintCommits(options, lintCommitsOptions, os.Stdout)
+		case "ldflags":
+			result, err = executeLdflags(options, ldflagsOptions)
+		case "promote":
+			result, err = executePromote(options, promoteOptions)
+		}
 	}
-	result, err := run(options.Dir, opts)
 	if err != nil {
 		return err
 	}
 
+	return writeResult(args, result)
+}
+
+// writeResult appends the result to the file given as argument (or the file
+// named by the environment variable given as $NAME), defaulting to stdout
+func writeResult(args []string, result string) error {
 	file := "-"
 	if len(args) == 1 {
 		arg := args[0]
@@ -90,11 +141,57 @@ func Execute(version FullVersion) error {
 		return err
 	}
 	defer output.Close()
-	fmt.Fprintf(output, "%s\n", *result)
+	fmt.Fprintf(output, "%s\n", result)
 
 	return nil
 }
 
+func executeDescribe(options ParserOptions) (string, error) {
+	result, err := describe.Describe(context.Background(), describeOptions(options))
+	if err != nil {
+		return "", err
+	}
+	return result.Output, nil
+}
+
+func describeOptions(options ParserOptions) describe.Options {
+	var info func(msg string)
+	if options.Verbose {
+		info = func(msg string) {
+			fmt.Fprintf(os.Stderr, "%s\n", msg)
+		}
+	}
+	return describe.Options{
+		Dir:                   options.Dir,
+		Backend:               options.Backend,
+		NoCache:               options.NoCache,
+		Fallback:              options.Fallback,
+		FallbackFrom:          options.FallbackFrom,
+		FallbackIfHigher:      options.FallbackIfHigher,
+		Base:                  options.Base,
+		AllowShallow:          options.AllowShallow,
+		AllowNoTags:           options.AllowNoTags,
+		AsOf:                  options.AsOf,
+		DropPrefix:            options.DropPrefix,
+		PrereleaseSuffix:      options.PrereleaseSuffix,
+		PrereleasePrefix:      options.PrereleasePrefix,
+		PrereleaseTimestamped: options.PrereleaseTimestamped,
+		TimestampSource:       options.TimestampSource,
+		TimestampLayout:       options.TimestampLayout,
+		NextRelease:           options.NextRelease,
+		NextPrerelease:        options.NextPrerelease,
+		Format:                options.Format,
+		Abbrev:                options.Abbrev,
+		OnCollision:           options.OnCollision,
+		Loose:                 options.Loose,
+		ExcludeMessages:       options.ExcludeMessage,
+		NotesRef:              options.NotesRef,
+		NoNotes:               options.NotesRef == "",
+		Warn:                  warn,
+		Info:                  info,
+	}
+}
+
 type FullVersion struct {
 	Version string
 	Commit  string
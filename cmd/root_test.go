@@ -2,36 +2,85 @@ package cmd
 
 import (
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
-	"github.com/choffmeister/git-describe-semver/internal"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestRun(t *testing.T) {
+func TestExecuteDescribe(t *testing.T) {
 	assert := assert.New(t)
 	dir, _ := ioutil.TempDir("", "example")
+	defer os.RemoveAll(dir)
 	author := object.Signature{Name: "Test", Email: "test@test.com"}
-	_, err := run(dir, internal.GenerateVersionOptions{PrereleasePrefix: "dev"})
+	options := ParserOptions{Dir: dir, Backend: "auto", Base: "nearest", PrereleasePrefix: "dev", OnCollision: "ignore"}
+	_, err := executeDescribe(options)
 	assert.Error(err)
 
 	repo, _ := git.PlainInit(dir, false)
 	worktree, _ := repo.Worktree()
-	_, err = run(dir, internal.GenerateVersionOptions{PrereleasePrefix: "dev"})
+	_, err = executeDescribe(options)
 	assert.Error(err)
 
 	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
 	repo.CreateTag("invalid", commit1, nil)
-	_, err = run(dir, internal.GenerateVersionOptions{PrereleasePrefix: "dev"})
+	_, err = executeDescribe(options)
 	assert.Error(err)
 
 	commit2, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
 	repo.CreateTag("v1.0.0", commit2, nil)
 
 	commit3, _ := worktree.Commit("second", &git.CommitOptions{Author: &author})
-	result, err := run(dir, internal.GenerateVersionOptions{PrereleasePrefix: "dev"})
+	result, err := executeDescribe(options)
 	assert.NoError(err)
-	assert.Equal("v1.0.1-dev.1.g"+commit3.String()[0:7], *result)
+	assert.Equal("v1.0.1-dev.1.g"+commit3.String()[0:7], result)
+	for _, abbrev := range []int{0, 12} {
+		opts := options
+		opts.Abbrev = &abbrev
+		result, err := executeDescribe(opts)
+		assert.NoError(err)
+		expected := "v1.0.1-dev.1"
+		if abbrev > 0 {
+			expected += ".g" + commit3.String()[0:abbrev]
+		}
+		assert.Equal(expected, result)
+	}
+	opts := options
+	opts.Format = "version=<version>"
+	opts.DropPrefix = true
+	result, err = executeDescribe(opts)
+	assert.NoError(err)
+	assert.Equal("version=1.0.1-dev.1.g"+commit3.String()[0:7], result)
+
+	worktree.Checkout(&git.CheckoutOptions{Hash: commit2})
+	commit4, _ := worktree.Commit("hotfix", &git.CommitOptions{Author: &author})
+	repo.CreateTag("v1.0.1", commit4, nil)
+	worktree.Checkout(&git.CheckoutOptions{Hash: commit3})
+	opts = options
+	opts.NextRelease = "patch"
+	opts.OnCollision = "fail"
+	_, err = executeDescribe(opts)
+	assert.Error(err)
+	opts.OnCollision = "advance"
+	result, err = executeDescribe(opts)
+	assert.NoError(err)
+	assert.Equal("v1.0.2", result)
+}
+
+func TestWriteResult(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "output")
+
+	assert.NoError(writeResult([]string{path}, "version=v1.0.0"))
+	t.Setenv("OUTPUT_FILE", path)
+	assert.NoError(writeResult([]string{"$OUTPUT_FILE"}, "version=v1.0.1"))
+	contents, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.Equal("version=v1.0.0\nversion=v1.0.1\n", string(contents))
+
+	t.Setenv("OUTPUT_FILE", "")
+	assert.Error(writeResult([]string{"$OUTPUT_FILE"}, "version=v1.0.2"))
 }
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/choffmeister/git-describe-semver/pkg/semver"
+)
+
+type SatisfiesOptions struct {
+	Args struct {
+		Version    string `positional-arg-name:"version" description:"The version to check"`
+		Constraint string `positional-arg-name:"constraint" description:"The constraint to check against (like \">=1.2.0 <2.0.0\" or \"^1.4\")"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func executeSatisfies(opts SatisfiesOptions) error {
+	version, err := semver.Parse(opts.Args.Version)
+	if err != nil {
+		return err
+	}
+	constraint, err := semver.ParseConstraint(opts.Args.Constraint)
+	if err != nil {
+		return err
+	}
+	if !constraint.Check(version) {
+		return fmt.Errorf("version %s does not satisfy %s", version, constraint)
+	}
+	return nil
+}
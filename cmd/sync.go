@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+	"github.com/choffmeister/git-describe-semver/pkg/describe"
+)
+
+type SyncOptions struct {
+	Check bool `long:"check" description:"Fail instead of updating should a manifest disagree with the version"`
+	Args  struct {
+		Manifests []string `positional-arg-name:"manifest" description:"The manifests to update, relative to the worktree directory"`
+	} `positional-args:"yes"`
+}
+
+func executeSync(options ParserOptions, opts SyncOptions, out io.Writer) error {
+	manifests := opts.Args.Manifests
+	if len(manifests) == 0 {
+		for _, name := range internal.ManifestNames {
+			if _, err := os.Stat(filepath.Join(options.Dir, name)); err == nil {
+				manifests = append(manifests, name)
+			}
+		}
+		if len(manifests) == 0 {
+			return fmt.Errorf("no manifest found in %s", options.Dir)
+		}
+	}
+	result, err := describe.Describe(context.Background(), describeOptions(options))
+	if err != nil {
+		return err
+	}
+	prefix := options.PrereleasePrefix
+	if prefix == "" {
+		prefix = "dev"
+	}
+	mismatches := 0
+	for _, manifest := range manifests {
+		changes, err := internal.SyncManifest(filepath.Join(options.Dir, manifest), describe.InternalVersion(result.Version), prefix, opts.Check)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if opts.Check {
+				fmt.Fprintf(out, "%s: %s is %s, expected %s\n", manifest, change.Field, change.From, change.To)
+			} else {
+				fmt.Fprintf(out, "%s: %s %s -> %s\n", manifest, change.Field, change.From, change.To)
+			}
+		}
+		mismatches += len(changes)
+	}
+	if opts.Check && mismatches > 0 {
+		return fmt.Errorf("%d manifest versions disagree with %s", mismatches, result.Version.String())
+	}
+	return nil
+}
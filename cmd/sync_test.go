@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteSync(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	defer os.RemoveAll(dir)
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	hash, err := worktree.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@test.com", When: time.Now()},
+	})
+	assert.NoError(err)
+	repo.CreateTag("v2.0.0", hash, nil)
+	options := ParserOptions{Dir: dir, Backend: "auto", Base: "nearest", PrereleasePrefix: "dev", OnCollision: "ignore"}
+	assert.NoError(os.WriteFile(filepath.Join(dir, "package.json"), []byte("{\"version\": \"1.0.0\"}\n"), 0o644))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "pyproject.toml"), []byte("[project]\nversion = \"1.0.0\"\n"), 0o644))
+
+	output := &bytes.Buffer{}
+	assert.EqualError(executeSync(options, SyncOptions{Check: true}, output), "2 manifest versions disagree with v2.0.0")
+	assert.Equal("package.json: version is 1.0.0, expected 2.0.0\npyproject.toml: version is 1.0.0, expected 2.0.0\n", output.String())
+
+	output.Reset()
+	assert.NoError(executeSync(options, SyncOptions{}, output))
+	assert.Equal("package.json: version 1.0.0 -> 2.0.0\npyproject.toml: version 1.0.0 -> 2.0.0\n", output.String())
+	contents, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	assert.NoError(err)
+	assert.Equal("{\"version\": \"2.0.0\"}\n", string(contents))
+
+	output.Reset()
+	opts := SyncOptions{Check: true}
+	opts.Args.Manifests = []string{"pyproject.toml"}
+	assert.NoError(executeSync(options, opts, output))
+	assert.Equal("", output.String())
+}
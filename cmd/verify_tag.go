@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+)
+
+const prePushHookMarker = "# Installed by git-describe-semver verify-tag"
+
+type VerifyTagOptions struct {
+	AllowSkip           bool `long:"allow-skip" description:"Allow skipping versions and lower tags of the same release line that are not ancestors"`
+	ConventionalCommits bool `long:"conventional-commits" description:"Require the bump level to match the Conventional Commits since the last release"`
+	PrePush             bool `long:"pre-push" description:"Verify the pushed tags as reported by git on stdin (pre-push hook mode)"`
+	InstallHook         bool `long:"install-hook" description:"Install a pre-push hook verifying pushed tags with the given flags"`
+	Force               bool `long:"force" description:"Overwrite an existing pre-push hook when installing"`
+	Args                struct {
+		Tag string `positional-arg-name:"tag" description:"The tag to verify (expected on HEAD should it not exist yet)"`
+	} `positional-args:"yes"`
+}
+
+// versionLikeTagRegexp matches tags that are meant to be versions, even if
+// they are no valid semver
+var versionLikeTagRegexp = regexp.MustCompile(`^[A-Za-z]*[-_]?\d`)
+
+func executeVerifyTag(options ParserOptions, opts VerifyTagOptions) error {
+	if opts.InstallHook {
+		return installPrePushHook(options, opts)
+	}
+	repo, err := internal.OpenRepository(options.Dir, options.Backend)
+	if err != nil {
+		return fmt.Errorf("unable to open git repository: %v", err)
+	}
+	verifyOpts := internal.VerifyTagOptions{
+		AllowSkip:           opts.AllowSkip,
+		ConventionalCommits: opts.ConventionalCommits,
+		Loose:               options.Loose,
+		ExcludeMessages:     options.ExcludeMessage,
+	}
+	if !opts.PrePush {
+		if opts.Args.Tag == "" {
+			return fmt.Errorf("the tag to verify is required")
+		}
+		return internal.VerifyTag(repo, opts.Args.Tag, verifyOpts)
+	}
+	tagNames, err := readPrePushTags(os.Stdin)
+	if err != nil {
+		return err
+	}
+	errs := []error{}
+	for _, tagName := range tagNames {
+		if !versionLikeTagRegexp.MatchString(tagName) {
+			continue
+		}
+		if err := internal.VerifyTag(repo, tagName, verifyOpts); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readPrePushTags returns the names of the tags being pushed (but not
+// deleted) from the lines "<local ref> <local sha> <remote ref> <remote sha>"
+func readPrePushTags(r io.Reader) ([]string, error) {
+	result := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || !strings.HasPrefix(fields[0], "refs/tags/") {
+			continue
+		}
+		if strings.Trim(fields[1], "0") == "" {
+			continue
+		}
+		result = append(result, strings.TrimPrefix(fields[0], "refs/tags/"))
+	}
+	return result, scanner.Err()
+}
+
+func installPrePushHook(options ParserOptions, opts VerifyTagOptions) error {
+	gitDir, workTree, err := internal.FindRepository(options.Dir)
+	if err != nil {
+		return fmt.Errorf("unable to open git repository: %v", err)
+	}
+	hooksDir, err := internal.GitHooksDir(gitDir, workTree)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(hooksDir, "pre-push")
+	if existing, err := os.ReadFile(path); err == nil && !strings.Contains(string(existing), prePushHookMarker) && !opts.Force {
+		return fmt.Errorf("pre-push hook %s already exists (use --force to overwrite it)", path)
+	}
+	args := []string{"git-describe-semver", "verify-tag", "--pre-push"}
+	if options.Backend != "" && options.Backend != internal.BackendAuto {
+		args = append(args, "--backend="+options.Backend)
+	}
+	if options.Loose {
+		args = append(args, "--loose")
+	}
+	if opts.AllowSkip {
+		args = append(args, "--allow-skip")
+	}
+	if opts.ConventionalCommits {
+		args = append(args, "--conventional-commits")
+		for _, pattern := range options.ExcludeMessage {
+			args = append(args, "'--exclude-message="+strings.ReplaceAll(pattern, "'", `'\''`)+"'")
+		}
+	}
+	script := "#!/bin/sh\n" + prePushHookMarker + "\nexec " + strings.Join(args, " ") + "\n"
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(script), 0o755)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadPrePushTags(t *testing.T) {
+	assert := assert.New(t)
+	zero := strings.Repeat("0", 40)
+	hash := strings.Repeat("a", 40)
+	input := strings.Join([]string{
+		"refs/tags/v1.0.0 " + hash + " refs/tags/v1.0.0 " + zero,
+		"refs/heads/main " + hash + " refs/heads/main " + hash,
+		"(delete) " + zero + " refs/tags/v0.9.0 " + hash,
+		"refs/tags/latest " + hash + " refs/tags/latest " + zero,
+	}, "\n")
+	tags, err := readPrePushTags(strings.NewReader(input))
+	assert.NoError(err)
+	assert.Equal([]string{"v1.0.0", "latest"}, tags)
+}
+
+func TestInstallPrePushHook(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	defer os.RemoveAll(dir)
+	git.PlainInit(dir, false)
+	path := filepath.Join(dir, ".git", "hooks", "pre-push")
+
+	assert.NoError(installPrePushHook(ParserOptions{Dir: dir}, VerifyTagOptions{AllowSkip: true}))
+	script, _ := os.ReadFile(path)
+	assert.Contains(string(script), "exec git-describe-semver verify-tag --pre-push --allow-skip\n")
+	assert.NoError(installPrePushHook(ParserOptions{Dir: dir, ExcludeMessage: []string{`^chore\(release\)`, "it's"}}, VerifyTagOptions{ConventionalCommits: true}))
+	script, _ = os.ReadFile(path)
+	assert.Contains(string(script), `exec git-describe-semver verify-tag --pre-push --conventional-commits '--exclude-message=^chore\(release\)' '--exclude-message=it'\''s'`+"\n")
+	assert.NoError(installPrePushHook(ParserOptions{Dir: dir}, VerifyTagOptions{}))
+
+	os.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), 0o755)
+	assert.Error(installPrePushHook(ParserOptions{Dir: dir}, VerifyTagOptions{}))
+	assert.NoError(installPrePushHook(ParserOptions{Dir: dir}, VerifyTagOptions{Force: true}))
+}
@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Name of the file git archive substitutes the version information into
+	ArchivalFileName = ".git_archival.txt"
+	// Contents of the archival file, compatible with setuptools-scm
+	ArchivalTemplate = "node: $Format:%H$\n" +
+		"node-date: $Format:%cI$\n" +
+		"describe-name: $Format:%(describe:tags=true,match=*[0-9]*)$\n" +
+		"ref-names: $Format:%D$\n"
+	// Line in .gitattributes that enables the substitution
+	ArchivalAttributes = ArchivalFileName + " export-subst"
+)
+
+// Archival is the version information of a git archive
+type Archival struct {
+	Node     string
+	NodeDate time.Time
+	// Output of git describe --tags (empty if there was no tag)
+	DescribeName string
+	// Tags pointing to the node
+	Tags []string
+}
+
+var archivalDescribeRegexp = regexp.MustCompile(`^(.+)-(\d+)-g([0-9a-f]+)$`)
+
+// FindArchival searches dir and its parents for the archival file
+func FindArchival(dir string) (string, error) {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(current, ArchivalFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", fmt.Errorf("%s not found: %w", ArchivalFileName, os.ErrNotExist)
+		}
+		current = parent
+	}
+}
+
+// ReadArchival parses the archival file at path, which must have been
+// substituted by git archive
+func ReadArchival(path string) (*Archival, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(string(contents), "$Format:") {
+		return nil, fmt.Errorf("%s has not been substituted by git archive (is \"%s\" missing in .gitattributes?)", path, ArchivalAttributes)
+	}
+	result := &Archival{}
+	for _, line := range strings.Split(string(contents), "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "node":
+			result.Node = value
+		case "node-date":
+			if value != "" {
+				if result.NodeDate, err = time.Parse(time.RFC3339, value); err != nil {
+					return nil, fmt.Errorf("invalid node-date %s in %s", value, path)
+				}
+			}
+		case "describe-name":
+			result.DescribeName = value
+		case "ref-names":
+			for _, ref := range strings.Split(value, ",") {
+				if tag := strings.TrimPrefix(strings.TrimSpace(ref), "tag: "); tag != strings.TrimSpace(ref) {
+					result.Tags = append(result.Tags, tag)
+				}
+			}
+		}
+	}
+	if result.Node == "" {
+		return nil, fmt.Errorf("%s has no node", path)
+	}
+	return result, nil
+}
+
+// Describe is the equivalent of GitDescribe for archives, based on the
+// output of git describe at the time the archive was created. Should the
+// described tag be no semver, the highest semver tag on the node is used.
+func (a Archival) Describe(opts GitDescribeOptions) (string, int, string, error) {
+	tagName, counter := a.DescribeName, 0
+	if match := archivalDescribeRegexp.FindStringSubmatch(tagName); match != nil && !containsString(a.Tags, tagName) {
+		tagName = match[1]
+		counter, _ = strconv.Atoi(match[2])
+	}
+	if tagName != "" && semVerParseTag(tagName, opts.Loose) == nil {
+		tagName, counter = HighestTag(a.Tags, nil, opts.Loose), 0
+		if tagName == "" {
+			return "", 0, "", fmt.Errorf("archive is described by %s, which is not a semantic version", a.DescribeName)
+		}
+	}
+	if tagName == "" && !opts.AllowNoTags {
+		return "", 0, "", IncompleteHistoryError{NoTags: true}
+	}
+	return tagName, counter, a.Node, nil
+}
+
+// AbbrevLength returns the length of the abbreviated hash in the output of
+// git describe, which git chose to be unique when creating the archive.
+// Defaults to 7 without hash.
+func (a Archival) AbbrevLength() int {
+	if match := archivalDescribeRegexp.FindStringSubmatch(a.DescribeName); match != nil {
+		return len(match[3])
+	}
+	return 7
+}
+
+// WriteArchivalFiles writes the archival file into the worktree and enables
+// its substitution in .gitattributes. An archival file with other contents
+// is only replaced if forced.
+func WriteArchivalFiles(workTree string, force bool) error {
+	path := filepath.Join(workTree, ArchivalFileName)
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil && string(existing) != ArchivalTemplate && !force {
+		return fmt.Errorf("%s already exists with other contents", path)
+	}
+	if err := os.WriteFile(path, []byte(ArchivalTemplate), 0o644); err != nil {
+		return err
+	}
+	attributesPath := filepath.Join(workTree, ".gitattributes")
+	attributes, err := os.ReadFile(attributesPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, line := range strings.Split(string(attributes), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && strings.TrimPrefix(fields[0], "/") == ArchivalFileName && containsString(fields[1:], "export-subst") {
+			return nil
+		}
+	}
+	if len(attributes) > 0 && !strings.HasSuffix(string(attributes), "\n") {
+		attributes = append(attributes, '\n')
+	}
+	return os.WriteFile(attributesPath, append(attributes, []byte(ArchivalAttributes+"\n")...), 0o644)
+}
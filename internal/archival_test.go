@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadArchival(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, ArchivalFileName)
+	test := func(contents string, expected *Archival, expectedErr string) {
+		assert.NoError(os.WriteFile(path, []byte(contents), 0o644))
+		actual, err := ReadArchival(path)
+		if expectedErr != "" {
+			assert.EqualError(err, expectedErr)
+			return
+		}
+		assert.NoError(err)
+		assert.Equal(expected, actual)
+	}
+
+	test(ArchivalTemplate, nil, path+" has not been substituted by git archive (is \".git_archival.txt export-subst\" missing in .gitattributes?)")
+	test("node: abc\nnode-date: 2020-01-02T03:04:05+01:00\ndescribe-name: v1.0.0-2-gabc\nref-names: HEAD -> main\n", &Archival{
+		Node:         "abc",
+		NodeDate:     time.Date(2020, 1, 2, 2, 4, 5, 0, time.UTC).In(time.FixedZone("", 3600)),
+		DescribeName: "v1.0.0-2-gabc",
+	}, "")
+	test("node: abc\nnode-date: \ndescribe-name: \nref-names: HEAD -> main, tag: v1.0.0, tag: latest, origin/main\n", &Archival{
+		Node: "abc",
+		Tags: []string{"v1.0.0", "latest"},
+	}, "")
+	test("describe-name: v1.0.0\n", nil, path+" has no node")
+}
+
+func TestArchivalDescribe(t *testing.T) {
+	assert := assert.New(t)
+	test := func(archival Archival, opts GitDescribeOptions, expectedTagName string, expectedCounter int, expectedErr string) {
+		tagName, counter, headHash, err := archival.Describe(opts)
+		if expectedErr != "" {
+			assert.EqualError(err, expectedErr)
+			return
+		}
+		assert.NoError(err)
+		assert.Equal(expectedTagName, tagName)
+		assert.Equal(expectedCounter, counter)
+		assert.Equal(archival.Node, headHash)
+	}
+
+	test(Archival{Node: "abc", DescribeName: "v1.0.0", Tags: []string{"v1.0.0"}}, GitDescribeOptions{}, "v1.0.0", 0, "")
+	test(Archival{Node: "abc", DescribeName: "v1.0.0-rc.1-12-gabc"}, GitDescribeOptions{}, "v1.0.0-rc.1", 12, "")
+	test(Archival{Node: "abc", DescribeName: "build-12", Tags: []string{"build-12", "v1.1.0"}}, GitDescribeOptions{}, "v1.1.0", 0, "")
+	test(Archival{Node: "abc", DescribeName: "build-12-3-gabc"}, GitDescribeOptions{}, "", 0, "archive is described by build-12-3-gabc, which is not a semantic version")
+	test(Archival{Node: "abc", DescribeName: "v1.2-3-gabc"}, GitDescribeOptions{Loose: true}, "v1.2", 3, "")
+	test(Archival{Node: "abc"}, GitDescribeOptions{}, "", 0, IncompleteHistoryError{NoTags: true}.Error())
+	test(Archival{Node: "abc"}, GitDescribeOptions{AllowNoTags: true}, "", 0, "")
+}
+
+func TestArchivalAbbrevLength(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(9, Archival{Node: "abc", DescribeName: "v1.0.0-12-gabc123456"}.AbbrevLength())
+	assert.Equal(7, Archival{Node: "abc", DescribeName: "v1.0.0"}.AbbrevLength())
+	assert.Equal(7, Archival{Node: "abc"}.AbbrevLength())
+}
+
+func TestWriteArchivalFiles(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	attributesPath := filepath.Join(dir, ".gitattributes")
+	assert.NoError(os.WriteFile(attributesPath, []byte("*.png binary"), 0o644))
+
+	assert.NoError(WriteArchivalFiles(dir, false))
+	assert.NoError(WriteArchivalFiles(dir, false))
+	contents, _ := os.ReadFile(filepath.Join(dir, ArchivalFileName))
+	assert.Equal(ArchivalTemplate, string(contents))
+	attributes, _ := os.ReadFile(attributesPath)
+	assert.Equal("*.png binary\n.git_archival.txt export-subst\n", string(attributes))
+
+	assert.NoError(os.WriteFile(filepath.Join(dir, ArchivalFileName), []byte("node: $Format:%H$\n"), 0o644))
+	assert.Error(WriteArchivalFiles(dir, false))
+	assert.NoError(WriteArchivalFiles(dir, true))
+	contents, _ = os.ReadFile(filepath.Join(dir, ArchivalFileName))
+	assert.Equal(ArchivalTemplate, string(contents))
+}
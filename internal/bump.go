@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var versionBumpTrailerRegexp = regexp.MustCompile(`(?i)^version-bump:\s*(\S*)\s*$`)
+
+// versionBumpTrailer returns the value of the Version-Bump trailer (major,
+// minor, patch or none) in the last paragraph of the message, or an empty
+// string if there is none
+func versionBumpTrailer(message string) (string, error) {
+	paragraphs := strings.Split(strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n")), "\n\n")
+	if len(paragraphs) < 2 {
+		return "", nil
+	}
+	result := ""
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		match := versionBumpTrailerRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		switch value := strings.ToLower(match[1]); value {
+		case "major", "minor", "patch", "none":
+			result = value
+		default:
+			return "", fmt.Errorf("invalid Version-Bump trailer %q (expected major, minor, patch or none)", match[1])
+		}
+	}
+	return result, nil
+}
+
+// GitNextRelease resolves the release to bump to (major, minor or patch) for
+// the non-merge commits between the base tag (all commits if empty) and head.
+// Commits may raise it with a Version-Bump trailer. Commits with
+// "Version-Bump: none" or matching the excluded messages are ignored.
+func GitNextRelease(repo Repository, nextRelease string, tagName string, headHash string, opts GitDescribeOptions) (string, error) {
+	if nextRelease == "" {
+		return "", nil
+	}
+	return gitBumpLevel(repo, nextRelease, false, tagName, headHash, opts)
+}
+
+// gitBumpLevel raises the level by the Version-Bump trailers of the commits
+// like GitNextRelease does. With conventional the Conventional Commit type
+// counts for commits without trailer, so that the level is empty should no
+// commit remain.
+func gitBumpLevel(repo Repository, level string, conventional bool, tagName string, headHash string, opts GitDescribeOptions) (string, error) {
+	excludeMessages, err := compileMessagePatterns(opts.ExcludeMessages)
+	if err != nil {
+		return "", err
+	}
+	reachable := map[string]bool{}
+	if tagName != "" {
+		tagHash, err := GitResolveBase(repo, tagName, opts)
+		if err != nil {
+			return "", err
+		}
+		if reachable, err = gitReachable(repo, tagHash); err != nil {
+			return "", err
+		}
+	}
+	commits, err := repo.LogMessages(headHash)
+	if err != nil {
+		return "", fmt.Errorf("unable to get history: %v", err)
+	}
+	result := level
+	for _, c := range commits {
+		if reachable[c.Hash] || len(c.Parents) > 1 || matchesAny(excludeMessages, c.Message) {
+			continue
+		}
+		bump, err := versionBumpTrailer(c.Message)
+		if err != nil {
+			return "", fmt.Errorf("commit %s: %v", c.Hash, err)
+		}
+		if bump == "" && conventional {
+			bump = conventionalBump([]string{c.Message})
+		}
+		if bumpRank(bump) > bumpRank(result) {
+			result = bump
+		}
+	}
+	return result, nil
+}
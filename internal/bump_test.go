@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionBumpTrailer(t *testing.T) {
+	assert := assert.New(t)
+	test := func(message string, expected string, expectedErr bool) {
+		actual, err := versionBumpTrailer(message)
+		if expectedErr {
+			assert.Error(err, message)
+			return
+		}
+		assert.NoError(err, message)
+		assert.Equal(expected, actual, message)
+	}
+
+	test("fix: foo", "", false)
+	test("fix: foo\n\nVersion-Bump: major\n", "major", false)
+	test("fix: foo\n\nBody\n\nReviewed-by: Z\nversion-bump: None", "none", false)
+	test("Version-Bump: minor", "", false)
+	test("fix: foo\n\nVersion-Bump: minor\n\nMore body", "", false)
+	test("fix: foo\n\nVersion-Bump: huge", "", true)
+}
+
+func TestGitNextRelease(t *testing.T) {
+	assert := assert.New(t)
+	dir, git := setUpCliRepository(t)
+	git("commit", "--allow-empty", "--quiet", "-m", "feat: initial")
+	git("tag", "v1.0.0")
+	repo, err := OpenRepository(dir, BackendGit)
+	assert.NoError(err)
+	opts := GitDescribeOptions{ExcludeMessages: []string{`^chore\(release\)`}}
+	test := func(nextRelease string, tagName string, expected string, expectedConventional string) {
+		head, err := repo.Head()
+		assert.NoError(err)
+		actual, err := GitNextRelease(repo, nextRelease, tagName, head, opts)
+		assert.NoError(err)
+		assert.Equal(expected, actual, nextRelease)
+		actual, err = gitBumpLevel(repo, "", true, tagName, head, opts)
+		assert.NoError(err)
+		assert.Equal(expectedConventional, actual, tagName)
+	}
+
+	test("", "v1.0.0", "", "")
+	test("patch", "v1.0.0", "patch", "")
+	test("patch", "", "patch", "minor")
+
+	git("commit", "--allow-empty", "--quiet", "-m", "feat: second\n\nVersion-Bump: none")
+	git("commit", "--allow-empty", "--quiet", "-m", "chore(release): feat!: not a change")
+	test("minor", "v1.0.0", "minor", "")
+
+	git("commit", "--allow-empty", "--quiet", "-m", "fix: third")
+	test("minor", "v1.0.0", "minor", "patch")
+
+	git("commit", "--allow-empty", "--quiet", "-m", "docs: fourth\n\nVersion-Bump: major")
+	test("patch", "v1.0.0", "major", "major")
+
+	git("commit", "--allow-empty", "--quiet", "-m", "fix: fifth\n\nVersion-Bump: gigantic")
+	head, _ := repo.Head()
+	_, err = GitNextRelease(repo, "patch", "v1.0.0", head, opts)
+	assert.Error(err)
+}
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// Name of the cache file inside the git directory
+	CacheFileName = "git-describe-semver.cache"
+	cacheVersion  = 2
+)
+
+type describeCacheFile struct {
+	Version int    `json:"version"`
+	State   string `json:"state"`
+	// Results of GitTags by options
+	Tags map[string]map[string]string `json:"tags"`
+	// Results of GitDescribe by options
+	Describe map[string]describeCacheEntry `json:"describe"`
+}
+
+type describeCacheEntry struct {
+	TagName  string `json:"tagName"`
+	Counter  int    `json:"counter"`
+	HeadHash string `json:"headHash"`
+}
+
+// GitTagsCached is like GitTags, but reuses the result of previous
+// invocations as long as HEAD and the refs did not change
+func GitTagsCached(repo Repository, opts GitDescribeOptions) (*map[string]string, error) {
+	cache, state := readDescribeCache(repo)
+	key := describeCacheKey(GitDescribeOptions{AsOf: opts.AsOf, Loose: opts.Loose})
+	if tags, found := cache.Tags[key]; found && state != "" {
+		return &tags, nil
+	}
+	tags, err := GitTags(repo, opts)
+	if err != nil {
+		return nil, err
+	}
+	cache.Tags[key] = *tags
+	writeDescribeCache(repo, state, cache)
+	return tags, nil
+}
+
+// GitDescribeCached is like GitDescribe, but reuses the result of previous
+// invocations as long as HEAD and the refs did not change
+func GitDescribeCached(repo Repository, opts GitDescribeOptions) (*string, *int, *string, error) {
+	cache, state := readDescribeCache(repo)
+	key := describeCacheKey(opts)
+	if entry, found := cache.Describe[key]; found && state != "" {
+		return &entry.TagName, &entry.Counter, &entry.HeadHash, nil
+	}
+	tagName, counter, headHash, err := GitDescribe(repo, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cache.Describe[key] = describeCacheEntry{TagName: *tagName, Counter: *counter, HeadHash: *headHash}
+	writeDescribeCache(repo, state, cache)
+	return tagName, counter, headHash, nil
+}
+
+// describeCacheKey returns the key of the options
+func describeCacheKey(opts GitDescribeOptions) string {
+	asOf := int64(0)
+	if !opts.AsOf.IsZero() {
+		asOf = opts.AsOf.Unix()
+	}
+	return fmt.Sprintf("base=%q asOf=%d allowShallow=%t allowNoTags=%t loose=%t excludeMessages=%q notesRef=%q",
+		opts.Base, asOf, opts.AllowShallow, opts.AllowNoTags, opts.Loose, opts.ExcludeMessages, opts.NotesRef)
+}
+
+// readDescribeCache returns the cache contents if still valid (empty
+// otherwise) together with the current refs state. The state is empty if it
+// cannot be determined, in which case the cache must not be used.
+func readDescribeCache(repo Repository) (describeCacheFile, string) {
+	empty := describeCacheFile{
+		Version:  cacheVersion,
+		Tags:     map[string]map[string]string{},
+		Describe: map[string]describeCacheEntry{},
+	}
+	state, err := gitRefsState(repo)
+	if err != nil {
+		return empty, ""
+	}
+	contents, err := os.ReadFile(filepath.Join(repo.GitDir(), CacheFileName))
+	if err != nil {
+		return empty, state
+	}
+	cache := describeCacheFile{}
+	if err := json.Unmarshal(contents, &cache); err != nil || cache.Version != cacheVersion || cache.State != state {
+		return empty, state
+	}
+	if cache.Tags == nil {
+		cache.Tags = empty.Tags
+	}
+	if cache.Describe == nil {
+		cache.Describe = empty.Describe
+	}
+	return cache, state
+}
+
+// writeDescribeCache stores the cache on a best effort basis, failures (like
+// read-only repositories) are ignored
+func writeDescribeCache(repo Repository, state string, cache describeCacheFile) {
+	if state == "" {
+		return
+	}
+	cache.State = state
+	contents, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	path := filepath.Join(repo.GitDir(), CacheFileName)
+	tmp, err := os.CreateTemp(repo.GitDir(), CacheFileName+".*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), path)
+}
+
+// gitRefsState fingerprints everything the describe result depends on: HEAD,
+// packed-refs, loose tag and notes refs and shallow commits
+func gitRefsState(repo Repository) (string, error) {
+	gitDir := repo.GitDir()
+	if gitDir == "" {
+		return "", os.ErrNotExist
+	}
+	commonDir := gitDir
+	if enableCommonDir, err := shouldEnableCommondDir(gitDir); err != nil {
+		return "", err
+	} else if enableCommonDir {
+		if commonDir, err = readCommonDir(gitDir); err != nil {
+			return "", err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(commonDir, "reftable")); err == nil {
+		// Refs are not stored as files, so changes cannot be detected
+		return "", os.ErrNotExist
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte("HEAD\x00" + head + "\x00"))
+	for _, name := range []string{"packed-refs", "shallow"} {
+		contents, err := os.ReadFile(filepath.Join(commonDir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		h.Write([]byte(name + "\x00"))
+		h.Write(contents)
+		h.Write([]byte("\x00"))
+	}
+	// Version notes are read from refs/notes
+	for _, refsDir := range []string{"tags", "notes"} {
+		dir := filepath.Join(commonDir, "refs", refsDir)
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(dir, path)
+			h.Write([]byte(refsDir + "/" + filepath.ToSlash(rel) + "\x00"))
+			h.Write(contents)
+			h.Write([]byte("\x00"))
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitDescribeCached(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	defer os.RemoveAll(dir)
+	author := object.Signature{Name: "Test", Email: "test@test.com"}
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	cachePath := filepath.Join(dir, GitDirName, CacheFileName)
+	test := func(expectedTagName string, expectedCounter int) {
+		tagName, counter, _, err := GitDescribeCached(NewGoGitRepository(repo), GitDescribeOptions{})
+		if assert.NoError(err) {
+			assert.Equal(expectedTagName, *tagName)
+			assert.Equal(expectedCounter, *counter)
+		}
+	}
+	tamper := func() {
+		contents, err := os.ReadFile(cachePath)
+		assert.NoError(err)
+		cache := describeCacheFile{}
+		assert.NoError(json.Unmarshal(contents, &cache))
+		for key, entry := range cache.Describe {
+			entry.Counter = 42
+			cache.Describe[key] = entry
+		}
+		contents, _ = json.Marshal(cache)
+		assert.NoError(os.WriteFile(cachePath, contents, 0o644))
+	}
+
+	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
+	repo.CreateTag("v1.0.0", commit1, nil)
+	worktree.Commit("second", &git.CommitOptions{Author: &author})
+	test("v1.0.0", 1)
+	assert.FileExists(cachePath)
+
+	// a valid cache is used as is
+	tamper()
+	test("v1.0.0", 42)
+
+	// moving HEAD invalidates the cache
+	commit3, _ := worktree.Commit("third", &git.CommitOptions{Author: &author})
+	test("v1.0.0", 2)
+
+	// creating tags invalidates the cache
+	tamper()
+	repo.CreateTag("v1.1.0", commit3, nil)
+	test("v1.1.0", 0)
+
+	// different options are cached separately
+	tamper()
+	tagName, counter, _, err := GitDescribeCached(NewGoGitRepository(repo), GitDescribeOptions{Base: BaseHighest})
+	assert.NoError(err)
+	assert.Equal("v1.1.0", *tagName)
+	assert.Equal(0, *counter)
+
+	// Keys only depend on the point in time, not its location
+	asOf := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(describeCacheKey(GitDescribeOptions{AsOf: asOf}), describeCacheKey(GitDescribeOptions{AsOf: asOf.In(time.FixedZone("", 3600))}))
+
+	tags, err := GitTagsCached(NewGoGitRepository(repo), GitDescribeOptions{})
+	assert.NoError(err)
+	assert.Equal(map[string]string{"v1.0.0": commit1.String(), "v1.1.0": commit3.String()}, *tags)
+	tags, err = GitTagsCached(NewGoGitRepository(repo), GitDescribeOptions{})
+	assert.NoError(err)
+	assert.Equal(map[string]string{"v1.0.0": commit1.String(), "v1.1.0": commit3.String()}, *tags)
+}
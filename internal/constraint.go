@@ -0,0 +1,265 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a version range like ">=1.2.0 <2.0.0", "^1.4" or
+// "1.2 - 1.4 || 2.x" following the npm semantics
+type Constraint struct {
+	raw string
+	// Alternatives (||) of comparators that all need to match
+	sets [][]comparator
+}
+
+type comparator struct {
+	op      string
+	version SemVer
+}
+
+// partialVersion is a version with optional (-1) minor and patch parts
+type partialVersion struct {
+	major      int
+	minor      int
+	patch      int
+	prerelease []string
+}
+
+var (
+	partialVersionRegexp = regexp.MustCompile(`^v?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?(?:-((?:[0-9A-Za-z-]+)(?:\.[0-9A-Za-z-]+)*))?(?:\+(?:[0-9A-Za-z-]+)(?:\.[0-9A-Za-z-]+)*)?$`)
+	hyphenRangeRegexp    = regexp.MustCompile(`^\s*(\S+)\s+-\s+(\S+)\s*$`)
+	comparatorRegexp     = regexp.MustCompile(`^(<=|>=|<|>|=|\^|~)?(.*)$`)
+)
+
+// ParseConstraint ...
+func ParseConstraint(str string) (*Constraint, error) {
+	c := &Constraint{raw: str}
+	for _, part := range strings.Split(str, "||") {
+		set, err := parseComparatorSet(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %q: %v", str, err)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+// Check reports whether the version satisfies the constraint. Like with npm,
+// prereleases only satisfy a constraint if one of its comparators has a
+// prerelease on the same major, minor and patch version.
+func (c Constraint) Check(v SemVer) bool {
+	for _, set := range c.sets {
+		if checkComparatorSet(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// String ...
+func (c Constraint) String() string {
+	return c.raw
+}
+
+func checkComparatorSet(set []comparator, v SemVer) bool {
+	for _, comp := range set {
+		if !comp.check(v) {
+			return false
+		}
+	}
+	if len(v.Prerelease) == 0 {
+		return true
+	}
+	for _, comp := range set {
+		allowed := comp.version
+		if len(allowed.Prerelease) > 0 && allowed.Major == v.Major && allowed.Minor == v.Minor && allowed.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func (comp comparator) check(v SemVer) bool {
+	c := v.Compare(comp.version)
+	switch comp.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	default:
+		return c == 0
+	}
+}
+
+func parseComparatorSet(str string) ([]comparator, error) {
+	str = strings.ReplaceAll(str, ",", " ")
+	if match := hyphenRangeRegexp.FindStringSubmatch(str); match != nil {
+		return parseHyphenRange(match[1], match[2])
+	}
+	// Join operators separated by whitespace from their version
+	tokens := []string{}
+	pending := ""
+	for _, field := range strings.Fields(str) {
+		if comparatorRegexp.FindStringSubmatch(field)[2] == "" {
+			pending += field
+			continue
+		}
+		tokens = append(tokens, pending+field)
+		pending = ""
+	}
+	if pending != "" {
+		return nil, fmt.Errorf("operator %s without version", pending)
+	}
+	if len(tokens) == 0 {
+		return []comparator{{op: ">=", version: SemVer{}}}, nil
+	}
+	result := []comparator{}
+	for _, token := range tokens {
+		match := comparatorRegexp.FindStringSubmatch(token)
+		v, err := parsePartialVersion(match[2])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, desugarComparator(match[1], v)...)
+	}
+	return result, nil
+}
+
+func parsePartialVersion(str string) (partialVersion, error) {
+	match := partialVersionRegexp.FindStringSubmatch(str)
+	if match == nil {
+		return partialVersion{}, fmt.Errorf("invalid version %q", str)
+	}
+	parts := []int{-1, -1, -1}
+	for i := 0; i < 3; i++ {
+		if match[i+1] == "" || strings.ContainsAny(match[i+1], "xX*") {
+			break
+		}
+		parts[i], _ = strconv.Atoi(match[i+1])
+	}
+	v := partialVersion{major: parts[0], minor: parts[1], patch: parts[2]}
+	if v.patch >= 0 {
+		v.prerelease = stringToSlice(match[4], ".")
+	}
+	return v, nil
+}
+
+// lower returns the lowest version matched by v
+func (v partialVersion) lower() SemVer {
+	return SemVer{Major: max(v.major, 0), Minor: max(v.minor, 0), Patch: max(v.patch, 0), Prerelease: v.prerelease}
+}
+
+// next returns the lowest version above all versions matched by v, which
+// needs at least a major version
+func (v partialVersion) next() SemVer {
+	switch {
+	case v.minor < 0:
+		return SemVer{Major: v.major + 1, Prerelease: []string{"0"}}
+	case v.patch < 0:
+		return SemVer{Major: v.major, Minor: v.minor + 1, Prerelease: []string{"0"}}
+	default:
+		return SemVer{Major: v.major, Minor: v.minor, Patch: v.patch + 1, Prerelease: []string{"0"}}
+	}
+}
+
+func desugarComparator(op string, v partialVersion) []comparator {
+	all := []comparator{{op: ">=", version: SemVer{}}}
+	none := []comparator{{op: "<", version: SemVer{Prerelease: []string{"0"}}}}
+	if v.major < 0 {
+		if op == "<" || op == ">" {
+			return none
+		}
+		return all
+	}
+	full := v.patch >= 0
+	switch op {
+	case "^":
+		upper := partialVersion{major: v.major, minor: -1, patch: -1}
+		if v.major == 0 && v.minor >= 0 {
+			upper = partialVersion{major: 0, minor: v.minor, patch: -1}
+			if v.minor == 0 && full {
+				upper = partialVersion{major: 0, minor: 0, patch: v.patch}
+			}
+		}
+		return []comparator{{op: ">=", version: v.lower()}, {op: "<", version: upper.next()}}
+	case "~":
+		upper := partialVersion{major: v.major, minor: v.minor, patch: -1}
+		return []comparator{{op: ">=", version: v.lower()}, {op: "<", version: upper.next()}}
+	case ">":
+		if full {
+			return []comparator{{op: ">", version: v.lower()}}
+		}
+		// Unlike the upper bounds, the lower bound must not allow prereleases
+		lower := v.next()
+		lower.Prerelease = nil
+		return []comparator{{op: ">=", version: lower}}
+	case ">=":
+		return []comparator{{op: ">=", version: v.lower()}}
+	case "<":
+		if full {
+			return []comparator{{op: "<", version: v.lower()}}
+		}
+		lower := v.lower()
+		lower.Prerelease = []string{"0"}
+		return []comparator{{op: "<", version: lower}}
+	case "<=":
+		if full {
+			return []comparator{{op: "<=", version: v.lower()}}
+		}
+		return []comparator{{op: "<", version: v.next()}}
+	default:
+		if full {
+			return []comparator{{op: "=", version: v.lower()}}
+		}
+		return []comparator{{op: ">=", version: v.lower()}, {op: "<", version: v.next()}}
+	}
+}
+
+func parseHyphenRange(from string, to string) ([]comparator, error) {
+	lower, err := parsePartialVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := parsePartialVersion(to)
+	if err != nil {
+		return nil, err
+	}
+	result := []comparator{{op: ">=", version: lower.lower()}}
+	switch {
+	case upper.major < 0:
+	case upper.patch < 0:
+		result = append(result, comparator{op: "<", version: upper.next()})
+	default:
+		result = append(result, comparator{op: "<=", version: upper.lower()})
+	}
+	return result, nil
+}
+
+// HighestTag returns the name of the highest semver tag satisfying the
+// constraint (if not nil), or an empty string if there is none
+func HighestTag(tagNames []string, constraint *Constraint, loose bool) string {
+	result := ""
+	var highest *SemVer
+	for _, tagName := range tagNames {
+		version := semVerParseTag(tagName, loose)
+		if version == nil || (constraint != nil && !constraint.Check(*version)) {
+			continue
+		}
+		if highest != nil {
+			c := highest.Compare(*version)
+			if c > 0 || (c == 0 && result < tagName) {
+				continue
+			}
+		}
+		result = tagName
+		highest = version
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstraint(t *testing.T) {
+	assert := assert.New(t)
+	test := func(constraint string, version string, expected bool) {
+		c, err := ParseConstraint(constraint)
+		if assert.NoError(err, constraint) {
+			assert.Equal(expected, c.Check(*SemVerParse(version)), "%s %s", constraint, version)
+		}
+	}
+
+	test(">=1.2.0 <2.0.0", "1.2.0", true)
+	test(">=1.2.0 <2.0.0", "1.9.9", true)
+	test(">=1.2.0 <2.0.0", "2.0.0", false)
+	test(">=1.2.0 <2.0.0", "1.1.9", false)
+	test(">= 1.2.0, < 2.0.0", "1.5.0", true)
+	test("1.2.3", "v1.2.3", true)
+	test("=1.2.3", "1.2.4", false)
+	test(">1.2.3", "1.2.4", true)
+	test("<=1.2.3", "1.2.3", true)
+
+	test("^1.4", "1.4.0", true)
+	test("^1.4", "1.9.0", true)
+	test("^1.4", "2.0.0", false)
+	test("^1.4", "1.3.9", false)
+	test("^0.2.3", "0.2.9", true)
+	test("^0.2.3", "0.3.0", false)
+	test("^0.0.3", "0.0.3", true)
+	test("^0.0.3", "0.0.4", false)
+	test("^0.0", "0.0.9", true)
+	test("^0.0", "0.1.0", false)
+	test("^0.x", "0.9.0", true)
+	test("^0.x", "1.0.0", false)
+
+	test("~1.2.3", "1.2.9", true)
+	test("~1.2.3", "1.3.0", false)
+	test("~1.2", "1.2.0", true)
+	test("~1", "1.9.0", true)
+	test("~1", "2.0.0", false)
+
+	test("1.x", "1.5.0", true)
+	test("1.x", "2.0.0", false)
+	test("1.2.*", "1.2.7", true)
+	test("1.2.*", "1.3.0", false)
+	test("*", "5.0.0", true)
+	test("", "5.0.0", true)
+	test(">1", "1.9.9", false)
+	test(">1", "2.0.0", true)
+	test(">1", "2.0.0-alpha", false)
+	test(">1.x", "2.0.0-alpha", false)
+	test(">1.2", "1.3.0-0", false)
+	test(">1.2", "1.3.0", true)
+	test(">1.2", "1.3.0", true)
+	test("<1.2", "1.1.9", true)
+	test("<1.2", "1.2.0", false)
+	test("<=1.2", "1.2.9", true)
+	test("<=1.2", "1.3.0", false)
+	test(">=1.2", "1.2.0", true)
+
+	test("1.2.3 - 2.3.4", "2.3.4", true)
+	test("1.2.3 - 2.3.4", "2.3.5", false)
+	test("1.2.3 - 2.3.4", "1.2.2", false)
+	test("1.2 - 2.3", "1.2.0", true)
+	test("1.2 - 2.3", "2.3.9", true)
+	test("1.2 - 2.3", "2.4.0", false)
+	test("1.2.3 - 2", "2.9.9", true)
+	test("1.2.3 - 2", "3.0.0", false)
+
+	test("^1.0 || ^3.0", "1.5.0", true)
+	test("^1.0 || ^3.0", "2.5.0", false)
+	test("^1.0 || ^3.0", "3.5.0", true)
+
+	// Prereleases only match comparators with prereleases on the same version
+	test(">=1.2.0 <2.0.0", "1.5.0-rc.1", false)
+	test(">=1.5.0-rc.1 <2.0.0", "1.5.0-rc.2", true)
+	test(">=1.5.0-rc.1 <2.0.0", "1.6.0-rc.1", false)
+	test(">=1.5.0-rc.1 <2.0.0", "1.5.0", true)
+	test("^1.2.3-beta.2", "1.2.3-beta.4", true)
+	test("^1.2.3-beta.2", "1.2.3-beta.1", false)
+	test("^1.2.3-beta.2", "1.2.4-beta.1", false)
+	test("^1.4", "2.0.0-rc.1", false)
+	test("<2.0.0", "2.0.0-rc.1", false)
+	test("*", "1.0.0-rc.1", false)
+
+	for _, invalid := range []string{"abc", ">=", "1.2.3.4", "^1.2 - 2", ">=1.2.0 <"} {
+		_, err := ParseConstraint(invalid)
+		assert.Error(err, invalid)
+	}
+}
+
+func TestHighestTag(t *testing.T) {
+	assert := assert.New(t)
+	tags := []string{"v1.3.0", "v1.4.2", "v1.5.0-rc.1", "v2.0.0", "latest", "1.4.2"}
+	test := func(constraint string, expected string) {
+		var c *Constraint
+		if constraint != "" {
+			c, _ = ParseConstraint(constraint)
+		}
+		assert.Equal(expected, HighestTag(tags, c, false), constraint)
+	}
+
+	test("", "v2.0.0")
+	test("^1.4", "1.4.2")
+	test("^1.5.0-rc.0", "v1.5.0-rc.1")
+	test("<1.4", "v1.3.0")
+	test(">=3", "")
+}
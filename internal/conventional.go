@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ConventionalCommit is the parsed message of a commit following the
+// Conventional Commits specification
+type ConventionalCommit struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+var conventionalCommitHeaderRegexp = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?: (.+)$`)
+
+// ParseConventionalCommit ...
+func ParseConventionalCommit(message string) (*ConventionalCommit, error) {
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	match := conventionalCommitHeaderRegexp.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if match == nil {
+		return nil, fmt.Errorf("header %q does not match <type>[(<scope>)][!]: <description>", lines[0])
+	}
+	commit := &ConventionalCommit{
+		Type:        strings.ToLower(match[1]),
+		Scope:       match[2],
+		Breaking:    match[3] == "!",
+		Description: match[4],
+	}
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			commit.Breaking = true
+		}
+	}
+	return commit, nil
+}
+
+// Bump returns the release the commit requires (major, minor or patch)
+func (c ConventionalCommit) Bump() string {
+	switch {
+	case c.Breaking:
+		return "major"
+	case c.Type == "feat":
+		return "minor"
+	default:
+		return "patch"
+	}
+}
+
+// conventionalBump returns the highest release required by the given commit
+// messages, treating messages that do not follow the specification as patches
+func conventionalBump(messages []string) string {
+	result := ""
+	for _, message := range messages {
+		bump := "patch"
+		if commit, err := ParseConventionalCommit(message); err == nil {
+			bump = commit.Bump()
+		}
+		if bumpRank(bump) > bumpRank(result) {
+			result = bump
+		}
+	}
+	return result
+}
+
+func bumpRank(bump string) int {
+	switch bump {
+	case "major":
+		return 3
+	case "minor":
+		return 2
+	case "patch":
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	assert := assert.New(t)
+	test := func(message string, expected *ConventionalCommit) {
+		actual, err := ParseConventionalCommit(message)
+		if expected == nil {
+			assert.Error(err, message)
+		} else if assert.NoError(err, message) {
+			assert.Equal(*expected, *actual)
+		}
+	}
+
+	test("feat: add flag", &ConventionalCommit{Type: "feat", Description: "add flag"})
+	test("fix(parser): handle empty input\n", &ConventionalCommit{Type: "fix", Scope: "parser", Description: "handle empty input"})
+	test("refactor!: drop old API", &ConventionalCommit{Type: "refactor", Breaking: true, Description: "drop old API"})
+	test("feat: new API\n\nBREAKING CHANGE: the old one is gone", &ConventionalCommit{Type: "feat", Breaking: true, Description: "new API"})
+	test("Feat(ui)!: dark mode", &ConventionalCommit{Type: "feat", Scope: "ui", Breaking: true, Description: "dark mode"})
+	test("Add flag", nil)
+	test("feat:missing space", nil)
+	test("feat(a)(b): nested", nil)
+
+	assert.Equal("", conventionalBump(nil))
+	assert.Equal("patch", conventionalBump([]string{"Merge branch", "chore: deps"}))
+	assert.Equal("minor", conventionalBump([]string{"fix: a", "feat: b"}))
+	assert.Equal("major", conventionalBump([]string{"feat: a", "fix!: b"}))
+}
@@ -2,11 +2,33 @@ package internal
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	// Do not check for collisions with existing tags
+	CollisionIgnore = "ignore"
+	// Warn about collisions with existing tags
+	CollisionWarn = "warn"
+	// Fail on collisions with existing tags
+	CollisionFail = "fail"
+	// Advance to the next version that does not collide with existing tags
+	CollisionAdvance = "advance"
+)
+
+const (
+	// Seconds since unix epoch
+	TimestampLayoutUnix = "unix"
+	// Date and time of day
+	TimestampLayoutDateTime = "YYYYMMDDHHMMSS"
+	// Date followed by the commit counter as separate identifier
+	TimestampLayoutDateCounter = "YYYYMMDD.N"
+)
+
 // GenerateVersionOptions ...
 type GenerateVersionOptions struct {
 	FallbackTagName       string
@@ -14,36 +36,90 @@ type GenerateVersionOptions struct {
 	PrereleaseSuffix      string
 	PrereleasePrefix      string
 	PrereleaseTimestamped bool
+	TimestampLayout       string
 	NextRelease           string
 	Format                string
+	// Number of hash characters (defaults to 7), 0 omits the hash
+	Abbrev *int
+	// Tag names mapped to commit hashes, used to detect collisions
+	ExistingTags map[string]string
+	Collision    string
+	Warn         func(msg string)
+	// Coerce legacy tag names like v1.2 into semver
+	Loose bool
+	// Prerelease channel (like rc) to compute the next prerelease tag for
+	NextPrerelease string
+	// Also use the fallback if it is higher than the tag (unless the tag is
+	// on HEAD)
+	FallbackIfHigher bool
 }
 
 // GenerateVersion ...
 func GenerateVersion(tagName string, counter int, headHash string, timestamp time.Time, opts GenerateVersionOptions) (*string, error) {
-	devPrerelease := []string{opts.PrereleasePrefix, strconv.Itoa(counter), "g" + (headHash)[0:7]}
+	version, err := GenerateSemVer(tagName, counter, headHash, timestamp, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := FormatVersion(*version, opts.Format)
+	return &result, nil
+}
+
+// FormatVersion replaces the <version> placeholder in format (if not empty)
+func FormatVersion(version SemVer, format string) string {
+	if format == "" {
+		return version.String()
+	}
+	return strings.ReplaceAll(format, "<version>", version.String())
+}
+
+// GenerateSemVer is like GenerateVersion, but returns the version before
+// applying the output format
+func GenerateSemVer(tagName string, counter int, headHash string, timestamp time.Time, opts GenerateVersionOptions) (*SemVer, error) {
+	abbrev := 7
+	if opts.Abbrev != nil {
+		abbrev = *opts.Abbrev
+	}
+	hashSegments := []string{}
+	if abbrev > 0 {
+		hashSegments = []string{"g" + abbreviateHash(headHash, abbrev)}
+	}
+	devPrerelease := append([]string{opts.PrereleasePrefix, strconv.Itoa(counter)}, hashSegments...)
 	if opts.PrereleaseTimestamped {
 		timestampUTC := timestamp.UTC()
 		timestampSegments := []string{
 			strconv.FormatInt(timestampUTC.UnixMilli()/1000, 10),
 		}
-		devPrerelease = []string{opts.PrereleasePrefix, strings.Join(timestampSegments, ""), "g" + (headHash)[0:7]}
+		switch opts.TimestampLayout {
+		case TimestampLayoutDateTime:
+			timestampSegments = []string{timestampUTC.Format("20060102150405")}
+		case TimestampLayoutDateCounter:
+			timestampSegments = []string{timestampUTC.Format("20060102"), strconv.Itoa(counter)}
+		}
+		devPrerelease = append(append([]string{opts.PrereleasePrefix}, timestampSegments...), hashSegments...)
 	}
 	if opts.PrereleaseSuffix != "" {
 		devPrerelease[len(devPrerelease)-1] = devPrerelease[len(devPrerelease)-1] + "-" + opts.PrereleaseSuffix
 	}
 	version := &SemVer{}
 	if tagName == "" {
-		version = SemVerParse(opts.FallbackTagName)
+		version = semVerParseTag(opts.FallbackTagName, opts.Loose)
 		if version == nil {
 			return nil, fmt.Errorf("unable to parse fallback tag")
 		}
 		version.Prerelease = devPrerelease
 	} else {
-		version = SemVerParse(tagName)
+		version = semVerParseTag(tagName, opts.Loose)
 		if version == nil {
 			return nil, fmt.Errorf("unable to parse tag")
 		}
-		if counter > 0 {
+		fallback := semVerParseTag(opts.FallbackTagName, opts.Loose)
+		if counter > 0 && opts.FallbackIfHigher && fallback != nil && fallback.Compare(*version) > 0 {
+			if fallback.Prefix == "" {
+				fallback.Prefix = version.Prefix
+			}
+			version = fallback
+			version.Prerelease = devPrerelease
+		} else if counter > 0 {
 			if len(version.Prerelease) > 0 {
 				version = &SemVer{
 					Prefix:        version.Prefix,
@@ -65,13 +141,118 @@ func GenerateVersion(tagName string, counter int, headHash string, timestamp tim
 			}
 		}
 	}
-	version.Bump(opts.NextRelease)
+	if opts.NextPrerelease != "" {
+		// Target the release the prerelease is for, which is the next patch
+		// release should the base version already be released
+		nextRelease := opts.NextRelease
+		if nextRelease == "" {
+			nextRelease = "patch"
+		}
+		version.Bump(nextRelease)
+		next, err := nextPrerelease(*version, opts.NextPrerelease, opts.ExistingTags, opts.Loose)
+		if err != nil {
+			return nil, err
+		}
+		version = next
+	} else {
+		version.Bump(opts.NextRelease)
+	}
+	if err := resolveCollision(version, headHash, opts); err != nil {
+		return nil, err
+	}
 	if opts.DropTagNamePrefix {
 		version.Prefix = ""
 	}
-	result := version.String()
-	if opts.Format != "" {
-		result = strings.ReplaceAll(opts.Format, "<version>", result)
+	return version, nil
+}
+
+func resolveCollision(version *SemVer, headHash string, opts GenerateVersionOptions) error {
+	if opts.Collision == "" || opts.Collision == CollisionIgnore {
+		return nil
 	}
-	return &result, nil
+	for {
+		tagName := findCollidingTag(*version, headHash, opts.ExistingTags, opts.Loose)
+		if tagName == "" {
+			return nil
+		}
+		switch opts.Collision {
+		case CollisionWarn:
+			if opts.Warn != nil {
+				opts.Warn(fmt.Sprintf("version %s collides with existing tag %s", version.String(), tagName))
+			}
+			return nil
+		case CollisionAdvance:
+			version.Advance(opts.NextRelease)
+		default:
+			return fmt.Errorf("version %s collides with existing tag %s", version.String(), tagName)
+		}
+	}
+}
+
+var prereleaseChannelRegexp = regexp.MustCompile(`^[0-9A-Za-z-]*[A-Za-z-][0-9A-Za-z-]*$`)
+
+// nextPrerelease returns <channel>.N of the given release with N following
+// the highest existing tag of the channel. Channels only progress in
+// precedence order (like alpha, beta, rc), so it fails should there be tags
+// of a higher channel or the release itself.
+func nextPrerelease(release SemVer, channel string, tags map[string]string, loose bool) (*SemVer, error) {
+	if !prereleaseChannelRegexp.MatchString(channel) {
+		return nil, fmt.Errorf("invalid prerelease channel %s", channel)
+	}
+	tagNames := []string{}
+	for tagName := range tags {
+		tagNames = append(tagNames, tagName)
+	}
+	sort.Strings(tagNames)
+	counter := 0
+	for _, tagName := range tagNames {
+		tag := semVerParseTag(tagName, loose)
+		if tag == nil || tag.Major != release.Major || tag.Minor != release.Minor || tag.Patch != release.Patch {
+			continue
+		}
+		if len(tag.Prerelease) == 0 {
+			return nil, fmt.Errorf("version %s has already been released as %s", release.String(), tagName)
+		}
+		c := comparePrereleaseIdentifier(tag.Prerelease[0], channel)
+		if c > 0 {
+			return nil, fmt.Errorf("cannot go back from %s to %s prereleases of %s", tagName, channel, release.String())
+		}
+		if c == 0 && len(tag.Prerelease) > 1 {
+			if n, err := strconv.Atoi(tag.Prerelease[1]); err == nil && n > counter {
+				counter = n
+			}
+		}
+	}
+	return &SemVer{
+		Prefix:     release.Prefix,
+		Major:      release.Major,
+		Minor:      release.Minor,
+		Patch:      release.Patch,
+		Prerelease: []string{channel, strconv.Itoa(counter + 1)},
+	}, nil
+}
+
+// findCollidingTag returns the name of a tag on another commit than head that
+// has the same precedence as the given version
+func findCollidingTag(version SemVer, headHash string, tags map[string]string, loose bool) string {
+	result := ""
+	for tagName, hash := range tags {
+		if hash == headHash {
+			continue
+		}
+		if tag := semVerParseTag(tagName, loose); tag != nil && tag.Compare(version) == 0 {
+			if result == "" || tagName < result {
+				result = tagName
+			}
+		}
+	}
+	return result
+}
+
+// abbreviateHash shortens SHA-1 as well as SHA-256 hashes to the given length
+func abbreviateHash(hash string, length int) string {
+	if len(hash) < length {
+		return hash
+	}
+	return hash[0:length]
 }
@@ -10,6 +10,9 @@ import (
 func TestGenerateVersion(t *testing.T) {
 	now, _ := time.Parse(time.RFC822Z, "01 Jan 20 02:03 -0000")
 	assert := assert.New(t)
+	abbrev := func(length int) *int {
+		return &length
+	}
 	test := func(inputTagName string, inputCounter int, inputHeadHash string, inputOpts GenerateVersionOptions, expected string) {
 		actual, err := GenerateVersion(inputTagName, inputCounter, inputHeadHash, now, inputOpts)
 		if assert.NoError(err) {
@@ -25,6 +28,11 @@ func TestGenerateVersion(t *testing.T) {
 	test("v0.0.0-rc.1+foobar", 1, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev"}, "v0.0.0-rc.1.dev.1.gabc1234+foobar")
 
 	test("", 1, "abc1234", GenerateVersionOptions{FallbackTagName: "v0.0.0", NextRelease: "patch"}, "v0.0.0")
+	test("v2.1.0", 3, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", FallbackTagName: "3.0.0", FallbackIfHigher: true}, "v3.0.0-dev.3.gabc1234")
+	test("v2.1.0", 3, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", FallbackTagName: "3.0.0", FallbackIfHigher: true, NextRelease: "patch"}, "v3.0.0")
+	test("v2.1.0", 3, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", FallbackTagName: "3.0.0"}, "v2.1.1-dev.3.gabc1234")
+	test("v2.1.0", 3, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", FallbackTagName: "2.1.0", FallbackIfHigher: true}, "v2.1.1-dev.3.gabc1234")
+	test("v2.1.0", 0, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", FallbackTagName: "3.0.0", FallbackIfHigher: true}, "v2.1.0")
 	test("", 1, "abc1234", GenerateVersionOptions{FallbackTagName: "v0.0.0", NextRelease: "minor"}, "v0.0.0")
 	test("", 1, "abc1234", GenerateVersionOptions{FallbackTagName: "v0.0.0", NextRelease: "major"}, "v0.0.0")
 	test("v0.0.0-rc.1+foobar", 1, "abc1234", GenerateVersionOptions{NextRelease: "patch"}, "v0.0.0")
@@ -53,10 +61,65 @@ func TestGenerateVersion(t *testing.T) {
 	test("0.0.0", 0, "abc1234", GenerateVersionOptions{PrereleasePrefix: "custom"}, "0.0.0")
 	test("0.0.0", 1, "abc1234", GenerateVersionOptions{PrereleasePrefix: "custom"}, "0.0.1-custom.1.gabc1234")
 
+	test("0.0.0", 1, "abc12345e4bd0dbc49e8b5b5a6c5b4b3d5ebf5c2", GenerateVersionOptions{PrereleasePrefix: "dev"}, "0.0.1-dev.1.gabc1234")
+	test("0.0.0", 1, "abc12345e4bd0dbc49e8b5b5a6c5b4b3d5ebf5c2", GenerateVersionOptions{PrereleasePrefix: "dev", Abbrev: abbrev(12)}, "0.0.1-dev.1.gabc12345e4bd")
+	test("0.0.0", 1, "abc12345e4bd0dbc49e8b5b5a6c5b4b3d5ebf5c2", GenerateVersionOptions{PrereleasePrefix: "dev", Abbrev: abbrev(0)}, "0.0.1-dev.1")
+	test("0.0.0", 1, "abc12345e4bd0dbc49e8b5b5a6c5b4b3d5ebf5c2", GenerateVersionOptions{PrereleasePrefix: "dev", Abbrev: abbrev(0), PrereleaseSuffix: "SNAPSHOT"}, "0.0.1-dev.1-SNAPSHOT")
+	test("0.0.0", 1, "abc12345e4bd0dbc49e8b5b5a6c5b4b3d5ebf5c2", GenerateVersionOptions{PrereleasePrefix: "dev", Abbrev: abbrev(0), PrereleaseTimestamped: true}, "0.0.1-dev.1577844180")
+	test("0.0.0", 1, "abc12345e4bd0dbc49e8b5b5a6c5b4b3d5ebf5c2e4bd0dbc49e8b5b5a6c5b4b3", GenerateVersionOptions{PrereleasePrefix: "dev"}, "0.0.1-dev.1.gabc1234")
+
 	test("0.0.0", 0, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", PrereleaseTimestamped: true}, "0.0.0")
 	test("0.0.0", 1, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", PrereleaseTimestamped: false}, "0.0.1-dev.1.gabc1234")
 	test("0.0.0", 1, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", PrereleaseTimestamped: true}, "0.0.1-dev.1577844180.gabc1234")
+	test("0.0.0", 1, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", PrereleaseTimestamped: true, TimestampLayout: TimestampLayoutUnix}, "0.0.1-dev.1577844180.gabc1234")
+	test("0.0.0", 1, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", PrereleaseTimestamped: true, TimestampLayout: TimestampLayoutDateTime}, "0.0.1-dev.20200101020300.gabc1234")
+	test("0.0.0", 3, "abc1234", GenerateVersionOptions{PrereleasePrefix: "dev", PrereleaseTimestamped: true, TimestampLayout: TimestampLayoutDateCounter}, "0.0.1-dev.20200101.3.gabc1234")
 
 	_, err := GenerateVersion("", 1, "abc1234", now, GenerateVersionOptions{PrereleasePrefix: "dev"})
 	assert.Error(err)
+
+	tags := map[string]string{"v1.2.3": "abc1234", "v1.2.4": "def5678", "v1.2.5": "def5679", "v1.4.0": "def5680"}
+	test("v1.2.3", 1, "abc9999", GenerateVersionOptions{NextRelease: "patch", ExistingTags: tags}, "v1.2.4")
+	test("v1.2.3", 1, "abc9999", GenerateVersionOptions{NextRelease: "patch", ExistingTags: tags, Collision: CollisionIgnore}, "v1.2.4")
+	test("v1.2.3", 1, "abc9999", GenerateVersionOptions{NextRelease: "patch", ExistingTags: tags, Collision: CollisionWarn}, "v1.2.4")
+	test("v1.2.3", 1, "abc9999", GenerateVersionOptions{NextRelease: "patch", ExistingTags: tags, Collision: CollisionAdvance}, "v1.2.6")
+	test("v1.2.3", 1, "abc9999", GenerateVersionOptions{NextRelease: "minor", ExistingTags: tags, Collision: CollisionAdvance}, "v1.3.0")
+	test("v1.3.0", 1, "abc9999", GenerateVersionOptions{NextRelease: "minor", ExistingTags: tags, Collision: CollisionAdvance}, "v1.5.0")
+	test("v1.2.4", 0, "def5678", GenerateVersionOptions{ExistingTags: tags, Collision: CollisionFail}, "v1.2.4")
+	test("v1.2.3", 1, "abc9999", GenerateVersionOptions{PrereleasePrefix: "dev", ExistingTags: tags, Collision: CollisionFail}, "v1.2.4-dev.1.gabc9999")
+
+	_, err = GenerateVersion("v1.2.3", 1, "abc9999", now, GenerateVersionOptions{NextRelease: "patch", ExistingTags: tags, Collision: CollisionFail})
+	assert.EqualError(err, "version v1.2.4 collides with existing tag v1.2.4")
+
+	warnings := []string{}
+	_, err = GenerateVersion("v1.2.3", 1, "abc9999", now, GenerateVersionOptions{NextRelease: "patch", ExistingTags: tags, Collision: CollisionWarn, Warn: func(msg string) {
+		warnings = append(warnings, msg)
+	}})
+	assert.NoError(err)
+	assert.Equal([]string{"version v1.2.4 collides with existing tag v1.2.4"}, warnings)
+
+	prereleaseTags := map[string]string{
+		"v1.2.3":         "a",
+		"v1.3.0-rc.1":    "b",
+		"v1.3.0-rc.2":    "c",
+		"v1.4.0-beta":    "d",
+		"v2.0.0-beta.1":  "e",
+		"v2.0.0-alpha.4": "f",
+	}
+	test("v1.3.0-rc.2", 0, "c", GenerateVersionOptions{NextPrerelease: "rc", ExistingTags: prereleaseTags}, "v1.3.0-rc.3")
+	test("v1.3.0-rc.2", 5, "x", GenerateVersionOptions{NextPrerelease: "rc", ExistingTags: prereleaseTags}, "v1.3.0-rc.3")
+	test("v1.3.0-rc.1", 0, "b", GenerateVersionOptions{NextPrerelease: "rc", ExistingTags: prereleaseTags}, "v1.3.0-rc.3")
+	test("v1.2.3", 0, "a", GenerateVersionOptions{NextPrerelease: "rc", NextRelease: "minor", ExistingTags: map[string]string{"v1.2.3": "a"}}, "v1.3.0-rc.1")
+	test("v1.2.3", 2, "x", GenerateVersionOptions{NextPrerelease: "rc", ExistingTags: prereleaseTags}, "v1.2.4-rc.1")
+	test("v1.2.3", 2, "x", GenerateVersionOptions{NextPrerelease: "beta", NextRelease: "minor", ExistingTags: map[string]string{"v1.3.0-alpha.2": "y"}}, "v1.3.0-beta.1")
+	test("v1.4.0-beta", 1, "x", GenerateVersionOptions{NextPrerelease: "beta", ExistingTags: prereleaseTags}, "v1.4.0-beta.1")
+	test("v2.0.0-beta.1", 1, "x", GenerateVersionOptions{NextPrerelease: "beta", ExistingTags: prereleaseTags}, "v2.0.0-beta.2")
+	test("", 1, "x", GenerateVersionOptions{FallbackTagName: "v0.1.0", NextPrerelease: "alpha"}, "v0.1.0-alpha.1")
+
+	_, err = GenerateVersion("v1.3.0-rc.2", 1, "x", now, GenerateVersionOptions{NextPrerelease: "beta", ExistingTags: prereleaseTags})
+	assert.EqualError(err, "cannot go back from v1.3.0-rc.1 to beta prereleases of v1.3.0")
+	_, err = GenerateVersion("v1.2.2", 1, "x", now, GenerateVersionOptions{NextPrerelease: "rc", ExistingTags: prereleaseTags})
+	assert.EqualError(err, "version v1.2.3 has already been released as v1.2.3")
+	_, err = GenerateVersion("v1.2.3", 1, "x", now, GenerateVersionOptions{NextPrerelease: "1"})
+	assert.EqualError(err, "invalid prerelease channel 1")
 }
@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/object"
+	"time"
 )
 
 const (
@@ -20,113 +20,386 @@ const (
 	CommonDirName = "commondir"
 )
 
-// GitTagMap ...
-func GitTagMap(repo git.Repository) (*map[string]string, error) {
-	iter, err := repo.Tags()
+const (
+	// Use the tag closest to HEAD as base version
+	BaseNearest = "nearest"
+	// Use the highest semver tag reachable from HEAD as base version
+	BaseHighest = "highest"
+)
+
+const (
+	TimestampSourceNow             = "now"
+	TimestampSourceCommit          = "commit"
+	TimestampSourceAuthor          = "author"
+	TimestampSourceTag             = "tag"
+	TimestampSourceSourceDateEpoch = "SOURCE_DATE_EPOCH"
+)
+
+// GitDescribeOptions ...
+type GitDescribeOptions struct {
+	Base string
+	// Ignore tags created after this point in time (ignored if zero)
+	AsOf time.Time
+	// Fall back instead of failing when the history is truncated by a shallow clone
+	AllowShallow bool
+	// Fall back instead of failing when the repository has no tags at all
+	AllowNoTags bool
+	// Coerce legacy tags like v1.2 into semver instead of ignoring them
+	Loose bool
+	// Regular expressions matching messages of commits that neither count
+	// towards the dev counter nor the next release
+	ExcludeMessages []string
+	// Notes ref with version notes that take precedence over tags on the
+	// same commit (disabled if empty)
+	NotesRef string
+}
+
+// IncompleteHistoryError is returned by GitDescribe when the base version
+// cannot be determined reliably because history or tags are missing
+type IncompleteHistoryError struct {
+	Shallow bool
+	NoTags  bool
+}
+
+func (e IncompleteHistoryError) Error() string {
+	switch {
+	case e.Shallow && e.NoTags:
+		return "repository is a shallow clone without any tags (fetch the full history and tags or allow shallow history explicitly)"
+	case e.Shallow:
+		return "repository is a shallow clone and the base version might be beyond the fetched history (fetch the full history or allow shallow history explicitly)"
+	default:
+		return "repository has no tags (fetch tags or allow missing tags explicitly)"
+	}
+}
+
+// GitTags returns all semver tags mapped to the hash of the commit they point to
+func GitTags(repo Repository, opts GitDescribeOptions) (*map[string]string, error) {
+	result, err := repo.Tags(func(name string) bool {
+		// Filter out tags that are not semver
+		return semVerParseTag(name, opts.Loose) != nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	tagMap := map[string]string{}
-	err = iter.ForEach(func(r *plumbing.Reference) error {
-		tag, _ := repo.TagObject(r.Hash())
-		if SemVerParse(r.Name().Short()) == nil {
-			// Filter out tags that are not semver
-			return nil
-		}
-		if tag == nil {
-			tagMap[r.Hash().String()] = r.Name().Short()
-		} else {
-			c, err := tag.Commit()
+	tags := map[string]string{}
+	for _, tag := range result {
+		if !opts.AsOf.IsZero() {
+			when, err := repo.TagDate(tag)
 			if err != nil {
-				return err
+				return nil, err
+			}
+			if when.After(opts.AsOf) {
+				continue
 			}
-			tagMap[c.Hash.String()] = r.Name().Short()
 		}
-		return nil
-	})
+		tags[tag.Name] = tag.Commit
+	}
+	return &tags, nil
+}
+
+// GitTagMap ...
+func GitTagMap(repo Repository, opts GitDescribeOptions) (*map[string]string, error) {
+	tags, err := GitTags(repo, opts)
 	if err != nil {
 		return nil, err
 	}
+	tagMap := map[string]string{}
+	for tagName, hash := range *tags {
+		// Keep the highest version should a commit have multiple tags
+		if existing, found := tagMap[hash]; found {
+			c := semVerParseTag(existing, opts.Loose).Compare(*semVerParseTag(tagName, opts.Loose))
+			if c > 0 || (c == 0 && existing < tagName) {
+				continue
+			}
+		}
+		tagMap[hash] = tagName
+	}
 	return &tagMap, nil
 }
 
-// GitDescribe ...
-func GitDescribe(repo git.Repository) (*string, *int, *string, error) {
-	type gitDescribeNode struct {
-		Commit   object.Commit
-		Distance int
-	}
+type gitDescribeNode struct {
+	Distance int
+}
 
-	head, err := repo.Head()
+// GitDescribe ...
+func GitDescribe(repo Repository, opts GitDescribeOptions) (*string, *int, *string, error) {
+	headHash, err := repo.Head()
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("unable to find head: %v", err)
 	}
-	headHash := head.Hash().String()
-	tags, err := GitTagMap(repo)
+	tags, err := GitTagMap(repo, opts)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("unable to get tags: %v", err)
 	}
-	commits, err := repo.Log(&git.LogOptions{
-		From:  head.Hash(),
-		Order: git.LogOrderCommitterTime,
-	})
+	// A version note on HEAD overrides the version, on an ancestor it is used
+	// as base version like a tag
+	notes, err := GitVersionNotes(repo, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for hash, version := range notes {
+		(*tags)[hash] = version
+	}
+	excludeMessages, err := compileMessagePatterns(opts.ExcludeMessages)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	log := repo.Log
+	if len(excludeMessages) > 0 {
+		log = repo.LogMessages
+	}
+	commits, err := log(headHash)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("unable to get log: %v", err)
 	}
+	// Excluded commits do not add to the distance of their parents
+	excluded := map[string]bool{}
+	for _, c := range commits {
+		if matchesAny(excludeMessages, c.Message) {
+			excluded[c.Hash] = true
+		}
+	}
+	weight := func(hash string) int {
+		if excluded[hash] {
+			return 0
+		}
+		return 1
+	}
 	state := map[string]gitDescribeNode{}
 	counter := 0
 	tagHash := ""
-	commits.ForEach(func(c *object.Commit) error {
-		node, found := state[c.Hash.String()]
+	for _, c := range commits {
+		node, found := state[c.Hash]
 		if !found {
 			node = gitDescribeNode{
-				Commit:   *c,
 				Distance: 0,
 			}
-			state[c.Hash.String()] = node
+			state[c.Hash] = node
 		}
-		c.Parents().ForEach(func(p *object.Commit) error {
-			_, found := state[p.Hash.String()]
+		for _, p := range c.Parents {
+			_, found := state[p]
 			if !found {
-				state[p.Hash.String()] = gitDescribeNode{
-					Commit:   *p,
-					Distance: node.Distance + 1,
+				state[p] = gitDescribeNode{
+					Distance: node.Distance + weight(c.Hash),
 				}
 			}
-			return nil
-		})
+		}
 
-		_, foundTag := (*tags)[c.Hash.String()]
+		_, foundTag := (*tags)[c.Hash]
 		if tagHash == "" && foundTag {
-			counter = state[c.Hash.String()].Distance
-			tagHash = c.Hash.String()
+			counter = state[c.Hash].Distance
+			tagHash = c.Hash
 		}
-		return nil
-	})
+	}
+	shallow, err := gitShallowBoundaryReached(repo, state)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to get shallow commits: %v", err)
+	}
+	if shallow && !opts.AllowShallow && (tagHash == "" || opts.Base == BaseHighest) {
+		noTags, err := gitHasNoTags(repo)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("unable to get tags: %v", err)
+		}
+		return nil, nil, nil, IncompleteHistoryError{Shallow: true, NoTags: noTags}
+	}
+	// A version note on HEAD overrides the version even under the highest
+	// base
+	if _, headNote := notes[headHash]; opts.Base == BaseHighest && !headNote {
+		hashes := []string{}
+		for hash := range state {
+			if _, foundTag := (*tags)[hash]; foundTag {
+				hashes = append(hashes, hash)
+			}
+		}
+		// Visit the tags by name, so that ties resolve the same on every run
+		sort.Slice(hashes, func(i, j int) bool {
+			if (*tags)[hashes[i]] != (*tags)[hashes[j]] {
+				return (*tags)[hashes[i]] < (*tags)[hashes[j]]
+			}
+			return hashes[i] < hashes[j]
+		})
+		var highest *SemVer
+		for _, hash := range hashes {
+			node := state[hash]
+			version := semVerParseTag((*tags)[hash], opts.Loose)
+			c := 1
+			if highest != nil {
+				c = version.Compare(*highest)
+			}
+			// Prefer the closer tag should two tags have the same precedence
+			if c > 0 || (c == 0 && node.Distance < counter) {
+				highest = version
+				counter = node.Distance
+				tagHash = hash
+			}
+		}
+	}
 	if tagHash == "" {
-		for _, node := range state {
-			if node.Distance+1 > counter {
-				counter = node.Distance + 1
+		if !opts.AllowNoTags {
+			noTags, err := gitHasNoTags(repo)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("unable to get tags: %v", err)
+			}
+			if noTags {
+				return nil, nil, nil, IncompleteHistoryError{NoTags: true}
+			}
+		}
+		for hash, node := range state {
+			if node.Distance+weight(hash) > counter {
+				counter = node.Distance + weight(hash)
 			}
 		}
+		// Even if all commits are excluded, HEAD must not get the fallback
+		// itself
+		if counter == 0 {
+			counter = 1
+		}
 		tagName := ""
 		return &tagName, &counter, &headHash, nil
 	}
+	// Excluded commits must not give a commit other than the tagged one the
+	// version of the tag
+	if counter == 0 && tagHash != headHash {
+		counter = 1
+	}
 	tagName := (*tags)[tagHash]
 	return &tagName, &counter, &headHash, nil
 }
 
-func OpenRepository(dir string) (*git.Repository, error) {
-	gitDir, err := FindGitDir(dir)
+func compileMessagePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	result := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message pattern %s: %v", pattern, err)
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func matchesAny(patterns []*regexp.Regexp, str string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(str) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitShallowBoundaryReached checks whether any of the visited commits is a
+// shallow commit, i.e. one whose parents have not been fetched
+func gitShallowBoundaryReached(repo Repository, visited map[string]gitDescribeNode) (bool, error) {
+	shallow, err := repo.Shallow()
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	for _, hash := range shallow {
+		if _, found := visited[hash]; found {
+			return true, nil
+		}
 	}
-	enableCommonDir, err := shouldEnableCommondDir(gitDir)
+	return false, nil
+}
+
+func gitHasNoTags(repo Repository) (bool, error) {
+	tagNames, err := repo.TagNames()
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	return len(tagNames) == 0, nil
+}
+
+// GitParseAsOf parses a point in time given either as date (meaning its end
+// in UTC), as unix timestamp prefixed with @ or as "commit-time" for the
+// committer time of HEAD
+func GitParseAsOf(repo Repository, value string) (*time.Time, error) {
+	if value == "commit-time" {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find head: %v", err)
+		}
+		c, err := repo.Commit(head)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find head commit: %v", err)
+		}
+		result := c.CommitterWhen
+		return &result, nil
+	}
+	if strings.HasPrefix(value, "@") {
+		seconds, err := strconv.ParseInt(value[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unix timestamp %s", value)
+		}
+		result := time.Unix(seconds, 0)
+		return &result, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if result, err := time.Parse(layout, value); err == nil {
+			return &result, nil
+		}
+	}
+	// Dates include the whole day (in UTC)
+	if result, err := time.Parse("2006-01-02", value); err == nil {
+		result = result.Add(24*time.Hour - time.Second)
+		return &result, nil
+	}
+	return nil, fmt.Errorf("invalid point in time %s", value)
+}
+
+// GitTimestamp determines the timestamp to use for timestamped prereleases.
+// The tag source falls back to the committer time of HEAD if there is no tag
+// (like for base versions from version notes).
+func GitTimestamp(repo Repository, source string, tagName string) (*time.Time, error) {
+	switch source {
+	case "", TimestampSourceNow:
+		result := time.Now()
+		return &result, nil
+	case TimestampSourceSourceDateEpoch:
+		value := os.Getenv("SOURCE_DATE_EPOCH")
+		if value == "" {
+			return nil, fmt.Errorf("SOURCE_DATE_EPOCH is not set")
+		}
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOURCE_DATE_EPOCH %s", value)
+		}
+		result := time.Unix(seconds, 0)
+		return &result, nil
+	case TimestampSourceTag:
+		if tagName != "" {
+			tags, err := repo.Tags(func(name string) bool { return name == tagName })
+			if err != nil {
+				return nil, fmt.Errorf("unable to find tag %s: %v", tagName, err)
+			}
+			if len(tags) > 1 {
+				return nil, fmt.Errorf("tag name %s is ambiguous (%d tags)", tagName, len(tags))
+			}
+			if len(tags) == 1 {
+				result, err := repo.TagDate(tags[0])
+				if err != nil {
+					return nil, fmt.Errorf("unable to find tag date: %v", err)
+				}
+				return &result, nil
+			}
+		}
+	case TimestampSourceCommit, TimestampSourceAuthor:
+	default:
+		return nil, fmt.Errorf("unknown timestamp source %s", source)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("unable to find head: %v", err)
+	}
+	c, err := repo.Commit(head)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find head commit: %v", err)
+	}
+	result := c.CommitterWhen
+	if source == TimestampSourceAuthor {
+		result = c.AuthorWhen
 	}
-	openOpts := &git.PlainOpenOptions{EnableDotGitCommonDir: enableCommonDir}
-	return git.PlainOpenWithOptions(dir, openOpts)
+	return &result, nil
 }
 
 func shouldEnableCommondDir(gitDir string) (bool, error) {
@@ -144,6 +417,72 @@ func shouldEnableCommondDir(gitDir string) (bool, error) {
 	return true, nil
 }
 
+func readCommonDir(gitDir string) (string, error) {
+	contents, err := os.ReadFile(filepath.Join(gitDir, CommonDirName))
+	if err != nil {
+		return "", err
+	}
+	return resolvePath(gitDir, strings.TrimSpace(string(contents))), nil
+}
+
+// ErrNotRepository is returned by FindRepository if no repository was found
+var ErrNotRepository = errors.New("not a git repository")
+
+// FindRepository discovers the git directory and the worktree (empty for bare
+// repositories) the same way git does: GIT_DIR and GIT_WORK_TREE take
+// precedence, otherwise dir and its parents are searched up to any of the
+// GIT_CEILING_DIRECTORIES.
+func FindRepository(dir string) (string, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	// Like git, relative paths of the environment are resolved against the
+	// working directory of the process
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", "", err
+	}
+	workTree := os.Getenv("GIT_WORK_TREE")
+	if workTree != "" {
+		workTree = resolvePath(cwd, workTree)
+	}
+	if gitDir := os.Getenv("GIT_DIR"); gitDir != "" {
+		if workTree == "" {
+			workTree = dir
+		}
+		return resolvePath(cwd, gitDir), workTree, nil
+	}
+
+	ceilingDirs := []string{}
+	for _, ceilingDir := range filepath.SplitList(os.Getenv("GIT_CEILING_DIRECTORIES")) {
+		if filepath.IsAbs(ceilingDir) {
+			ceilingDirs = append(ceilingDirs, filepath.Clean(ceilingDir))
+		}
+	}
+	current := dir
+	for {
+		gitDir, err := FindGitDir(current)
+		if err == nil {
+			if workTree == "" {
+				workTree = current
+			}
+			return gitDir, workTree, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", "", err
+		}
+		if isBareGitDir(current) {
+			return current, workTree, nil
+		}
+		parent := filepath.Dir(current)
+		if parent == current || containsString(ceilingDirs, parent) {
+			return "", "", fmt.Errorf("%w (or any of the parent directories): %s", ErrNotRepository, dir)
+		}
+		current = parent
+	}
+}
+
 func FindGitDir(dir string) (string, error) {
 	gitDirPath := filepath.Join(dir, GitDirName)
 	st, err := os.Stat(gitDirPath)
@@ -166,5 +505,31 @@ func FindGitDir(dir string) (string, error) {
 
 	gitdir := strings.Split(line[len(GitDirPrefix):], "\n")[0]
 	gitdir = strings.TrimSpace(gitdir)
-	return gitdir, nil
+	return resolvePath(dir, gitdir), nil
+}
+
+// isBareGitDir checks whether dir itself is a git directory
+func isBareGitDir(dir string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func resolvePath(base string, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
 }
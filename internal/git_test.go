@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -13,6 +14,127 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestGitTags(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	author := object.Signature{Name: "Test", Email: "test@test.com"}
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+
+	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
+	repo.CreateTag("v1.0.0", commit1, nil)
+	repo.CreateTag("v1.0.1", commit1, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Foo Bar", Email: "foo@bar.com"},
+		Message: "Version 1.0.1",
+	})
+	repo.CreateTag("latest", commit1, nil)
+	tags, err := GitTags(NewGoGitRepository(repo), GitDescribeOptions{})
+	assert.NoError(err)
+	assert.Equal(map[string]string{
+		"v1.0.0": commit1.String(),
+		"v1.0.1": commit1.String(),
+	}, *tags)
+}
+
+func TestGitTagsAsOf(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	signature := func(days int) *object.Signature {
+		return &object.Signature{Name: "Test", Email: "test@test.com", When: now.AddDate(0, 0, days)}
+	}
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	test := func(asOf time.Time, expected map[string]string) {
+		tags, err := GitTags(NewGoGitRepository(repo), GitDescribeOptions{AsOf: asOf})
+		assert.NoError(err)
+		assert.Equal(expected, *tags)
+	}
+
+	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: signature(0)})
+	repo.CreateTag("v1.0.0", commit1, nil)
+	commit2, _ := worktree.Commit("second", &git.CommitOptions{Author: signature(2)})
+	repo.CreateTag("v1.1.0", commit2, nil)
+	repo.CreateTag("v1.0.1", commit1, &git.CreateTagOptions{Tagger: signature(4), Message: "Version 1.0.1"})
+
+	test(time.Time{}, map[string]string{"v1.0.0": commit1.String(), "v1.0.1": commit1.String(), "v1.1.0": commit2.String()})
+	test(now.AddDate(0, 0, 5), map[string]string{"v1.0.0": commit1.String(), "v1.0.1": commit1.String(), "v1.1.0": commit2.String()})
+	test(now.AddDate(0, 0, 3), map[string]string{"v1.0.0": commit1.String(), "v1.1.0": commit2.String()})
+	test(now.AddDate(0, 0, 1), map[string]string{"v1.0.0": commit1.String()})
+	test(now.AddDate(0, 0, -1), map[string]string{})
+
+	// Tags created on the given date are included
+	asOf, err := GitParseAsOf(NewGoGitRepository(repo), "2020-01-03")
+	assert.NoError(err)
+	test(*asOf, map[string]string{"v1.0.0": commit1.String(), "v1.1.0": commit2.String()})
+}
+
+func TestGitParseAsOf(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	worktree.Commit("first", &git.CommitOptions{Author: &object.Signature{Name: "Test", Email: "test@test.com", When: when}})
+	test := func(input string, expected time.Time) {
+		actual, err := GitParseAsOf(NewGoGitRepository(repo), input)
+		if assert.NoError(err) {
+			assert.True(expected.Equal(*actual), "%s != %s", expected, *actual)
+		}
+	}
+
+	test("commit-time", when)
+	test("@1577934245", when)
+	test("2020-01-02T03:04:05Z", when)
+	test("2020-01-02T03:04:05", when)
+	test("2020-01-02 03:04:05", when)
+	test("2020-01-02", time.Date(2020, 1, 2, 23, 59, 59, 0, time.UTC))
+	_, err := GitParseAsOf(NewGoGitRepository(repo), "yesterday")
+	assert.Error(err)
+}
+
+func TestGitTimestamp(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	authored := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	committed := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	tagged := time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	commit1, _ := worktree.Commit("first", &git.CommitOptions{
+		Author:    &object.Signature{Name: "Test", Email: "test@test.com", When: authored},
+		Committer: &object.Signature{Name: "Test", Email: "test@test.com", When: committed},
+	})
+	repo.CreateTag("v1.0.0", commit1, nil)
+	repo.CreateTag("v1.0.1", commit1, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test", Email: "test@test.com", When: tagged},
+		Message: "Version 1.0.1",
+	})
+	test := func(source string, tagName string, expected time.Time) {
+		actual, err := GitTimestamp(NewGoGitRepository(repo), source, tagName)
+		if assert.NoError(err) {
+			assert.True(expected.Equal(*actual), "%s != %s", expected, *actual)
+		}
+	}
+
+	test(TimestampSourceCommit, "", committed)
+	test(TimestampSourceAuthor, "", authored)
+	test(TimestampSourceTag, "v1.0.0", committed)
+	test(TimestampSourceTag, "v1.0.1", tagged)
+	test(TimestampSourceTag, "", committed)
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1577836800")
+	test(TimestampSourceSourceDateEpoch, "", authored)
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	_, err := GitTimestamp(NewGoGitRepository(repo), TimestampSourceSourceDateEpoch, "")
+	assert.Error(err)
+
+	before := time.Now()
+	actual, err := GitTimestamp(NewGoGitRepository(repo), TimestampSourceNow, "")
+	assert.NoError(err)
+	assert.False(actual.Before(before))
+}
+
 func TestGitTagMap(t *testing.T) {
 	assert := assert.New(t)
 	dir, _ := ioutil.TempDir("", "example")
@@ -20,12 +142,12 @@ func TestGitTagMap(t *testing.T) {
 	repo, _ := git.PlainInit(dir, false)
 	worktree, _ := repo.Worktree()
 
-	tags, _ := GitTagMap(*repo)
+	tags, _ := GitTagMap(NewGoGitRepository(repo), GitDescribeOptions{})
 	assert.Equal(map[string]string{}, *tags)
 
 	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
 	tag1, _ := repo.CreateTag("v1.0.0", commit1, nil)
-	tags, _ = GitTagMap(*repo)
+	tags, _ = GitTagMap(NewGoGitRepository(repo), GitDescribeOptions{})
 	assert.Equal(commit1.String(), tag1.Hash().String())
 	assert.Equal(map[string]string{
 		tag1.Hash().String(): "v1.0.0",
@@ -40,7 +162,7 @@ func TestGitTagMap(t *testing.T) {
 		Message: "Version 2.0.0",
 	})
 	assert.NotEqual(commit2.String(), tag2.Hash().String())
-	tags, _ = GitTagMap(*repo)
+	tags, _ = GitTagMap(NewGoGitRepository(repo), GitDescribeOptions{})
 	assert.Equal(map[string]string{
 		commit1.String(): "v1.0.0",
 		commit2.String(): "v2.0.0",
@@ -55,11 +177,19 @@ func TestGitTagMap(t *testing.T) {
 		Message: "Not a semver version tag",
 	})
 	assert.NotEqual(commit3.String(), tag3.Hash().String())
-	tags, _ = GitTagMap(*repo)
+	tags, _ = GitTagMap(NewGoGitRepository(repo), GitDescribeOptions{})
 	assert.Equal(map[string]string{
 		commit1.String(): "v1.0.0",
 		commit2.String(): "v2.0.0",
 	}, *tags)
+
+	repo.CreateTag("v0.9.0", commit1, nil)
+	repo.CreateTag("v2.0.1", commit2, nil)
+	tags, _ = GitTagMap(NewGoGitRepository(repo), GitDescribeOptions{})
+	assert.Equal(map[string]string{
+		commit1.String(): "v1.0.0",
+		commit2.String(): "v2.0.1",
+	}, *tags)
 }
 
 func TestGitDescribe(t *testing.T) {
@@ -68,10 +198,10 @@ func TestGitDescribe(t *testing.T) {
 	author := object.Signature{Name: "Test", Email: "test@test.com"}
 	repo, _ := git.PlainInit(dir, false)
 	worktree, _ := repo.Worktree()
-	_, _, _, err := GitDescribe(*repo)
+	_, _, _, err := GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{})
 	assert.Error(err)
 	test := func(expectedTagName string, expectedCounter int, expectedHeadHash string) {
-		actualTagName, actualCounter, actualHeadHash, err := GitDescribe(*repo)
+		actualTagName, actualCounter, actualHeadHash, err := GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{AllowNoTags: true})
 		assert.NoError(err)
 		assert.Equal(expectedTagName, *actualTagName)
 		assert.Equal(expectedCounter, *actualCounter)
@@ -80,6 +210,8 @@ func TestGitDescribe(t *testing.T) {
 
 	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
 	test("", 1, commit1.String())
+	_, _, _, err = GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{})
+	assert.Equal(IncompleteHistoryError{NoTags: true}, err)
 
 	repo.CreateTag("v1.0.0", commit1, nil)
 	test("v1.0.0", 0, commit1.String())
@@ -100,10 +232,10 @@ func TestGitDescribeWithBranch(t *testing.T) {
 	author := object.Signature{Name: "Test", Email: "test@test.com"}
 	repo, _ := git.PlainInit(dir, false)
 	worktree, _ := repo.Worktree()
-	_, _, _, err := GitDescribe(*repo)
+	_, _, _, err := GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{})
 	assert.Error(err)
 	test := func(expectedTagName string, expectedCounter int, expectedHeadHash string) {
-		actualTagName, actualCounter, actualHeadHash, err := GitDescribe(*repo)
+		actualTagName, actualCounter, actualHeadHash, err := GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{AllowNoTags: true})
 		assert.NoError(err)
 		assert.Equal(expectedTagName, *actualTagName)
 		assert.Equal(expectedCounter, *actualCounter)
@@ -130,6 +262,87 @@ func TestGitDescribeWithBranch(t *testing.T) {
 	test("v2.0.0", 1, commit4.String())
 }
 
+func TestGitDescribeShallow(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	author := object.Signature{Name: "Test", Email: "test@test.com"}
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+
+	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
+	commit2, _ := worktree.Commit("second", &git.CommitOptions{Author: &author})
+	commit3, _ := worktree.Commit("third", &git.CommitOptions{Author: &author})
+	repo.Storer.SetShallow([]plumbing.Hash{commit2})
+
+	_, _, _, err := GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{})
+	assert.Equal(IncompleteHistoryError{Shallow: true, NoTags: true}, err)
+
+	repo.CreateTag("latest", commit3, nil)
+	_, _, _, err = GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{})
+	assert.Equal(IncompleteHistoryError{Shallow: true}, err)
+	_, _, _, err = GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{AllowNoTags: true})
+	assert.Equal(IncompleteHistoryError{Shallow: true}, err)
+
+	tagName, _, _, err := GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{AllowShallow: true})
+	assert.NoError(err)
+	assert.Equal("", *tagName)
+
+	repo.CreateTag("v1.0.0", commit1, nil)
+	_, _, _, err = GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{Base: BaseHighest})
+	assert.Equal(IncompleteHistoryError{Shallow: true}, err)
+
+	repo.CreateTag("v1.1.0", commit3, nil)
+	tagName, _, _, err = GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{})
+	assert.NoError(err)
+	assert.Equal("v1.1.0", *tagName)
+}
+
+func TestGitDescribeWithHighestBase(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	now := time.Now()
+	author := func(minutes int) *object.Signature {
+		return &object.Signature{Name: "Test", Email: "test@test.com", When: now.Add(time.Duration(minutes) * time.Minute)}
+	}
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	test := func(base string, expectedTagName string, expectedCounter int, expectedHeadHash string) {
+		actualTagName, actualCounter, actualHeadHash, err := GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{Base: base})
+		assert.NoError(err)
+		assert.Equal(expectedTagName, *actualTagName)
+		assert.Equal(expectedCounter, *actualCounter)
+		assert.Equal(expectedHeadHash, *actualHeadHash)
+	}
+
+	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: author(1)})
+	repo.CreateTag("v1.0.0", commit1, nil)
+	test(BaseNearest, "v1.0.0", 0, commit1.String())
+	test(BaseHighest, "v1.0.0", 0, commit1.String())
+
+	commit2, _ := worktree.Commit("release", &git.CommitOptions{Author: author(2)})
+	repo.CreateTag("v2.0.0", commit2, nil)
+	commit3, _ := worktree.Commit("release fix", &git.CommitOptions{Author: author(3)})
+
+	worktree.Checkout(&git.CheckoutOptions{Hash: commit1})
+	commit4, _ := worktree.Commit("mainline", &git.CommitOptions{Author: author(4)})
+	repo.CreateTag("v1.9.0", commit4, nil)
+
+	commit5, _ := worktree.Commit("merge", &git.CommitOptions{Author: author(5), Parents: []plumbing.Hash{commit4, commit3}})
+	test(BaseNearest, "v1.9.0", 1, commit5.String())
+	test(BaseHighest, "v2.0.0", 2, commit5.String())
+
+	repo.CreateTag("v1.9.1", commit5, nil)
+	test(BaseNearest, "v1.9.1", 0, commit5.String())
+	test(BaseHighest, "v2.0.0", 2, commit5.String())
+
+	// Tags of the same precedence at the same distance resolve by name
+	repo.CreateTag("v3.0.0+b", commit3, nil)
+	repo.CreateTag("v3.0.0+a", commit4, nil)
+	for i := 0; i < 10; i++ {
+		test(BaseHighest, "v3.0.0+a", 1, commit5.String())
+	}
+}
+
 func setUpDotGitDirTest(assert *assert.Assertions) (string, string) {
 	testDir, err := os.MkdirTemp("", "test")
 	assert.NoError(err, "failed to create temp dir")
@@ -161,6 +374,39 @@ func setUpDotGitFileTest(assert *assert.Assertions) (string, string, string) {
 	return testDir, actualDotGitPath, wtPath
 }
 
+func TestGitDescribeExcludeMessages(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	author := object.Signature{Name: "Test", Email: "test@test.com"}
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	test := func(excludeMessages []string, expectedTagName string, expectedCounter int) {
+		actualTagName, actualCounter, _, err := GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{AllowNoTags: true, ExcludeMessages: excludeMessages})
+		assert.NoError(err)
+		assert.Equal(expectedTagName, *actualTagName)
+		assert.Equal(expectedCounter, *actualCounter)
+	}
+	patterns := []string{`^chore\(release\)`, `\[skip version\]`}
+
+	commit1, _ := worktree.Commit("chore(release): 1.0.0", &git.CommitOptions{Author: &author})
+	test(patterns, "", 1)
+	test(nil, "", 1)
+
+	repo.CreateTag("v1.0.0", commit1, nil)
+	test(patterns, "v1.0.0", 0)
+	// An excluded commit right after the tag must not get its version
+	worktree.Commit("chore(release): x", &git.CommitOptions{Author: &author})
+	test(patterns, "v1.0.0", 1)
+	worktree.Commit("feat: second", &git.CommitOptions{Author: &author})
+	worktree.Commit("docs: third\n\n[skip version]", &git.CommitOptions{Author: &author})
+	worktree.Commit("chore(release): 1.1.0", &git.CommitOptions{Author: &author})
+	test(patterns, "v1.0.0", 1)
+	test(nil, "v1.0.0", 4)
+
+	_, _, _, err := GitDescribe(NewGoGitRepository(repo), GitDescribeOptions{ExcludeMessages: []string{"("}})
+	assert.Error(err)
+}
+
 func TestFindGitDir(t *testing.T) {
 	t.Run(".git is a directory", func(t *testing.T) {
 		assert := assert.New(t)
@@ -182,6 +428,136 @@ func TestFindGitDir(t *testing.T) {
 	})
 }
 
+func TestFindRepository(t *testing.T) {
+	setUpEnv := func(t *testing.T, gitDir string, workTree string, ceilingDirs string) {
+		t.Setenv("GIT_DIR", gitDir)
+		t.Setenv("GIT_WORK_TREE", workTree)
+		t.Setenv("GIT_CEILING_DIRECTORIES", ceilingDirs)
+	}
+	t.Run("from a subdirectory", func(t *testing.T) {
+		assert := assert.New(t)
+		setUpEnv(t, "", "", "")
+		testDir, gitDirPath := setUpDotGitDirTest(assert)
+		defer os.RemoveAll(testDir)
+		subDir := filepath.Join(testDir, "services", "api")
+		assert.NoError(os.MkdirAll(subDir, 0750))
+
+		gitDir, workTree, err := FindRepository(subDir)
+		assert.NoError(err)
+		assert.Equal(gitDirPath, gitDir)
+		assert.Equal(testDir, workTree)
+	})
+	t.Run(".git file with relative path", func(t *testing.T) {
+		assert := assert.New(t)
+		setUpEnv(t, "", "", "")
+		testDir, actualDotGitPath, wtPath := setUpDotGitFileTest(assert)
+		defer os.RemoveAll(testDir)
+		err := os.WriteFile(filepath.Join(wtPath, GitDirName), []byte(GitDirPrefix+"../actual\n"), 0666)
+		assert.NoError(err)
+
+		gitDir, workTree, err := FindRepository(wtPath)
+		assert.NoError(err)
+		assert.Equal(actualDotGitPath, gitDir)
+		assert.Equal(wtPath, workTree)
+	})
+	t.Run("GIT_DIR and GIT_WORK_TREE", func(t *testing.T) {
+		assert := assert.New(t)
+		testDir, actualDotGitPath, wtPath := setUpDotGitFileTest(assert)
+		defer os.RemoveAll(testDir)
+
+		setUpEnv(t, actualDotGitPath, "", "")
+		gitDir, workTree, err := FindRepository(testDir)
+		assert.NoError(err)
+		assert.Equal(actualDotGitPath, gitDir)
+		assert.Equal(testDir, workTree)
+
+		// Relative paths are resolved against the working directory, not dir
+		t.Chdir(testDir)
+		setUpEnv(t, "actual", "my_worktree", "")
+		gitDir, workTree, err = FindRepository(wtPath)
+		assert.NoError(err)
+		assert.Equal(actualDotGitPath, gitDir)
+		assert.Equal(wtPath, workTree)
+	})
+	t.Run("GIT_CEILING_DIRECTORIES", func(t *testing.T) {
+		assert := assert.New(t)
+		testDir, _ := setUpDotGitDirTest(assert)
+		defer os.RemoveAll(testDir)
+		subDir := filepath.Join(testDir, "services", "api")
+		assert.NoError(os.MkdirAll(subDir, 0750))
+
+		setUpEnv(t, "", "", filepath.Join(testDir, "services"))
+		_, _, err := FindRepository(subDir)
+		assert.Error(err)
+
+		setUpEnv(t, "", "", testDir)
+		_, _, err = FindRepository(filepath.Join(testDir, "services"))
+		assert.Error(err)
+
+		setUpEnv(t, "", "", filepath.Dir(testDir))
+		_, _, err = FindRepository(subDir)
+		assert.NoError(err)
+	})
+	t.Run("bare repository", func(t *testing.T) {
+		assert := assert.New(t)
+		setUpEnv(t, "", "", "")
+		dir, _ := ioutil.TempDir("", "example")
+		defer os.RemoveAll(dir)
+		_, err := git.PlainInit(dir, true)
+		assert.NoError(err)
+
+		gitDir, workTree, err := FindRepository(dir)
+		assert.NoError(err)
+		assert.Equal(dir, gitDir)
+		assert.Equal("", workTree)
+	})
+}
+
+func TestOpenRepository(t *testing.T) {
+	t.Setenv("GIT_DIR", "")
+	t.Setenv("GIT_WORK_TREE", "")
+	t.Setenv("GIT_CEILING_DIRECTORIES", "")
+	t.Run("from a subdirectory", func(t *testing.T) {
+		assert := assert.New(t)
+		dir, _ := ioutil.TempDir("", "example")
+		defer os.RemoveAll(dir)
+		author := object.Signature{Name: "Test", Email: "test@test.com"}
+		repo, _ := git.PlainInit(dir, false)
+		worktree, _ := repo.Worktree()
+		commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
+		repo.CreateTag("v1.0.0", commit1, nil)
+		subDir := filepath.Join(dir, "services", "api")
+		assert.NoError(os.MkdirAll(subDir, 0750))
+
+		opened, err := OpenRepository(subDir, BackendGoGit)
+		if assert.NoError(err) {
+			tagName, counter, _, err := GitDescribe(opened, GitDescribeOptions{})
+			assert.NoError(err)
+			assert.Equal("v1.0.0", *tagName)
+			assert.Equal(0, *counter)
+		}
+	})
+	t.Run("bare repository", func(t *testing.T) {
+		assert := assert.New(t)
+		dir, _ := ioutil.TempDir("", "example")
+		defer os.RemoveAll(dir)
+		author := object.Signature{Name: "Test", Email: "test@test.com"}
+		repo, _ := git.PlainInit(dir, false)
+		worktree, _ := repo.Worktree()
+		commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
+		repo.CreateTag("v1.0.0", commit1, nil)
+		worktree.Commit("second", &git.CommitOptions{Author: &author})
+
+		opened, err := OpenRepository(filepath.Join(dir, GitDirName), BackendGoGit)
+		if assert.NoError(err) {
+			tagName, counter, _, err := GitDescribe(opened, GitDescribeOptions{})
+			assert.NoError(err)
+			assert.Equal("v1.0.0", *tagName)
+			assert.Equal(1, *counter)
+		}
+	})
+}
+
 func TestShouldEnableCommonDir(t *testing.T) {
 	t.Run(".git is a directory", func(t *testing.T) {
 		assert := assert.New(t)
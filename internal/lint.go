@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultConventionalTypes are the commit types allowed by default, as used
+// by the Angular convention
+var DefaultConventionalTypes = []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"}
+
+// LintOptions ...
+type LintOptions struct {
+	// Allowed commit types (any if empty)
+	Types []string
+	// Allowed scopes (any if empty)
+	Scopes []string
+	// Maximum length of the header (unlimited if zero)
+	MaxHeaderLength int
+}
+
+// LintResult ...
+type LintResult struct {
+	Commit     string   `json:"commit"`
+	Header     string   `json:"header"`
+	Violations []string `json:"violations"`
+}
+
+var breakingChangeFooterRegexp = regexp.MustCompile(`(?i)^breaking[- _]?changes?\b`)
+
+// LintCommitMessage returns the violations of the Conventional Commits rules
+func LintCommitMessage(message string, opts LintOptions) []string {
+	violations := []string{}
+	lines := strings.Split(strings.TrimRight(message, "\n"), "\n")
+	header := lines[0]
+	if opts.MaxHeaderLength > 0 && len([]rune(header)) > opts.MaxHeaderLength {
+		violations = append(violations, fmt.Sprintf("header is longer than %d characters", opts.MaxHeaderLength))
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		violations = append(violations, "body must be separated from the header by an empty line")
+	}
+	commit, err := ParseConventionalCommit(header)
+	if err != nil {
+		violations = append(violations, "header does not match \"<type>[(<scope>)][!]: <description>\"")
+	} else {
+		if len(opts.Types) > 0 && !containsString(opts.Types, commit.Type) {
+			violations = append(violations, fmt.Sprintf("type %q is not allowed (allowed: %s)", commit.Type, strings.Join(opts.Types, ", ")))
+		}
+		if commit.Scope != "" && len(opts.Scopes) > 0 && !containsString(opts.Scopes, commit.Scope) {
+			violations = append(violations, fmt.Sprintf("scope %q is not allowed (allowed: %s)", commit.Scope, strings.Join(opts.Scopes, ", ")))
+		}
+	}
+	for i, line := range lines[1:] {
+		if !breakingChangeFooterRegexp.MatchString(line) {
+			continue
+		}
+		token, description, found := strings.Cut(line, ":")
+		if !found || (token != "BREAKING CHANGE" && token != "BREAKING-CHANGE") || !strings.HasPrefix(description, " ") || strings.TrimSpace(description) == "" {
+			violations = append(violations, fmt.Sprintf("breaking change footer %q must be \"BREAKING CHANGE: <description>\"", line))
+		} else if lines[i] != "" && !isFooterLine(lines[i]) {
+			violations = append(violations, "breaking change footer must be preceded by an empty line")
+		}
+	}
+	return violations
+}
+
+var footerLineRegexp = regexp.MustCompile(`^(?:[A-Za-z-]+|BREAKING CHANGE)(?:: | #)`)
+
+func isFooterLine(line string) bool {
+	return footerLineRegexp.MatchString(line)
+}
+
+// GitLintCommits lints the non-merge commits reachable from "to" but not from
+// "from" (all commits if empty)
+func GitLintCommits(repo Repository, from string, to string, opts LintOptions) ([]LintResult, error) {
+	commits, err := repo.LogMessages(to)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get history: %v", err)
+	}
+	excluded := map[string]bool{}
+	if from != "" {
+		if excluded, err = gitReachable(repo, from); err != nil {
+			return nil, err
+		}
+	}
+	result := []LintResult{}
+	for _, c := range commits {
+		if excluded[c.Hash] || len(c.Parents) > 1 {
+			continue
+		}
+		result = append(result, LintResult{
+			Commit:     c.Hash,
+			Header:     strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0],
+			Violations: LintCommitMessage(c.Message, opts),
+		})
+	}
+	return result, nil
+}
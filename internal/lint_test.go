@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintCommitMessage(t *testing.T) {
+	assert := assert.New(t)
+	opts := LintOptions{Types: DefaultConventionalTypes, Scopes: []string{"cli", "git"}, MaxHeaderLength: 40}
+	test := func(message string, expected ...string) {
+		if expected == nil {
+			expected = []string{}
+		}
+		assert.Equal(expected, LintCommitMessage(message, opts), message)
+	}
+
+	test("feat: add flag")
+	test("fix(git)!: handle shallow clones\n\nBREAKING CHANGE: requires git 2.20\n")
+	test("fix: handle shallow clones\n\nBody\n\nReviewed-by: Z\nBREAKING-CHANGE: requires git 2.20")
+	test("add flag", "header does not match \"<type>[(<scope>)][!]: <description>\"")
+	test("wip: add flag", "type \"wip\" is not allowed (allowed: build, chore, ci, docs, feat, fix, perf, refactor, revert, style, test)")
+	test("feat(api): add flag", "scope \"api\" is not allowed (allowed: cli, git)")
+	test("feat: add a flag with a very long description", "header is longer than 40 characters")
+	test("feat: add flag\nBody", "body must be separated from the header by an empty line")
+	test("feat: add flag\n\nbreaking change: flag removed", "breaking change footer \"breaking change: flag removed\" must be \"BREAKING CHANGE: <description>\"")
+	test("feat: add flag\n\nBREAKING CHANGES: flag removed", "breaking change footer \"BREAKING CHANGES: flag removed\" must be \"BREAKING CHANGE: <description>\"")
+	test("feat: add flag\n\nBody\nBREAKING CHANGE: flag removed", "breaking change footer must be preceded by an empty line")
+}
+
+func TestGitLintCommits(t *testing.T) {
+	assert := assert.New(t)
+	dir, git := setUpCliRepository(t)
+	git("commit", "--allow-empty", "--quiet", "-m", "initial")
+	git("tag", "v1.0.0")
+	git("commit", "--allow-empty", "--quiet", "-m", "feat: first")
+	commit1 := git("rev-parse", "HEAD")
+	git("checkout", "--quiet", "-b", "other")
+	git("commit", "--allow-empty", "--quiet", "-m", "second")
+	commit2 := git("rev-parse", "HEAD")
+	git("checkout", "--quiet", "main")
+	git("merge", "--no-ff", "--quiet", "-m", "Merge branch 'other'", "other")
+
+	for _, backend := range []string{BackendGoGit, BackendGit} {
+		repo, err := OpenRepository(dir, backend)
+		assert.NoError(err)
+		from, err := repo.ResolveRevision("v1.0.0")
+		assert.NoError(err)
+		to, err := repo.ResolveRevision("HEAD")
+		assert.NoError(err)
+		results, err := GitLintCommits(repo, from, to, LintOptions{Types: DefaultConventionalTypes})
+		assert.NoError(err)
+		assert.ElementsMatch([]LintResult{
+			{Commit: commit1, Header: "feat: first", Violations: []string{}},
+			{Commit: commit2, Header: "second", Violations: []string{"header does not match \"<type>[(<scope>)][!]: <description>\""}},
+		}, results, backend)
+
+		all, err := GitLintCommits(repo, "", to, LintOptions{})
+		assert.NoError(err)
+		assert.Len(all, 3, backend)
+	}
+}
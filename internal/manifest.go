@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ManifestField is a version in a project manifest, located by its byte
+// offsets so that it can be replaced without touching the formatting
+type ManifestField struct {
+	Name  string
+	Value string
+	Start int
+	End   int
+}
+
+// ManifestFields returns the version fields of the manifest, the main
+// version being the first. Supported are VERSION, package.json, Chart.yaml
+// (version and appVersion), Cargo.toml, pyproject.toml and pom.xml.
+func ManifestFields(path string, contents []byte) ([]ManifestField, error) {
+	var fields []ManifestField
+	var err error
+	switch filepath.Base(path) {
+	case "VERSION":
+		fields = versionFileFields(contents)
+	case "package.json":
+		fields, err = jsonManifestFields(contents)
+	case "Chart.yaml":
+		fields = yamlManifestFields(contents, "version", "appVersion")
+	case "Cargo.toml":
+		fields = tomlManifestFields(contents, "package", "workspace.package")
+	case "pyproject.toml":
+		fields = tomlManifestFields(contents, "project", "tool.poetry")
+	case "pom.xml":
+		fields, err = pomManifestFields(contents)
+	default:
+		return nil, fmt.Errorf("unsupported manifest %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	if len(fields) == 0 || fields[0].Name != "version" {
+		return nil, fmt.Errorf("%s has no version", path)
+	}
+	return fields, nil
+}
+
+// ReadManifestVersion returns the main version of the manifest at path
+func ReadManifestVersion(path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	fields, err := ManifestFields(path, contents)
+	if err != nil {
+		return "", err
+	}
+	return fields[0].Value, nil
+}
+
+func versionFileFields(contents []byte) []ManifestField {
+	offset := 0
+	for _, line := range strings.SplitAfter(string(contents), "\n") {
+		if value := strings.TrimSpace(line); value != "" {
+			start := offset + strings.Index(line, value)
+			return []ManifestField{{Name: "version", Value: value, Start: start, End: start + len(value)}}
+		}
+		offset += len(line)
+	}
+	return nil
+}
+
+type jsonContainer struct {
+	object    bool
+	expectKey bool
+}
+
+// jsonManifestFields locates the top-level version of package.json
+func jsonManifestFields(contents []byte) ([]ManifestField, error) {
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	stack := []jsonContainer{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			stack = append(stack, jsonContainer{object: token == json.Delim('{'), expectKey: token == json.Delim('{')})
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 || !stack[len(stack)-1].object {
+			continue
+		}
+		top := &stack[len(stack)-1]
+		if top.expectKey && len(stack) == 1 && token == "version" {
+			value, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("version is no string")
+			}
+			end := int(decoder.InputOffset()) - 1
+			start := bytes.LastIndexByte(contents[:end], '"') + 1
+			return []ManifestField{{Name: "version", Value: str, Start: start, End: end}}, nil
+		}
+		// Keys and (complete) values alternate within objects
+		top.expectKey = !top.expectKey
+	}
+}
+
+// yamlManifestFields locates top-level scalar keys of a YAML file
+func yamlManifestFields(contents []byte, keys ...string) []ManifestField {
+	result := []ManifestField{}
+	for _, key := range keys {
+		r := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(key) + `:[ \t]*(["']?)([^"'\s#]*)["']?[ \t]*(?:#.*)?$`)
+		if match := r.FindSubmatchIndex(contents); match != nil {
+			result = append(result, ManifestField{Name: key, Value: string(contents[match[4]:match[5]]), Start: match[4], End: match[5]})
+		}
+	}
+	return result
+}
+
+var (
+	tomlTableRegexp   = regexp.MustCompile(`^\s*\[([^\[\]]+)\]\s*(?:#.*)?$`)
+	tomlVersionRegexp = regexp.MustCompile(`^\s*version\s*=\s*(["'])([^"']*)["']`)
+)
+
+// tomlManifestFields locates the version key of the first of the given
+// tables that has one
+func tomlManifestFields(contents []byte, tables ...string) []ManifestField {
+	offset := 0
+	table := ""
+	found := map[string]ManifestField{}
+	for _, line := range strings.SplitAfter(string(contents), "\n") {
+		if match := tomlTableRegexp.FindStringSubmatch(line); match != nil {
+			table = strings.TrimSpace(match[1])
+		} else if match := tomlVersionRegexp.FindStringSubmatchIndex(line); match != nil && containsString(tables, table) {
+			if _, exists := found[table]; !exists {
+				found[table] = ManifestField{Name: "version", Value: line[match[4]:match[5]], Start: offset + match[4], End: offset + match[5]}
+			}
+		}
+		offset += len(line)
+	}
+	for _, table := range tables {
+		if field, ok := found[table]; ok {
+			return []ManifestField{field}
+		}
+	}
+	return nil
+}
+
+// pomManifestFields locates the version of the project, which falls back to
+// the version of the parent project
+func pomManifestFields(contents []byte) ([]ManifestField, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(contents))
+	path := []string{}
+	var parent *ManifestField
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		case xml.CharData:
+			location := strings.Join(path, "/")
+			if location != "project/version" && location != "project/parent/version" {
+				continue
+			}
+			value := strings.TrimSpace(string(t))
+			start += strings.Index(string(contents[start:decoder.InputOffset()]), value)
+			field := ManifestField{Name: "version", Value: value, Start: start, End: start + len(value)}
+			if location == "project/version" {
+				return []ManifestField{field}, nil
+			}
+			parent = &field
+		}
+	}
+	if parent != nil {
+		return []ManifestField{*parent}, nil
+	}
+	return nil, nil
+}
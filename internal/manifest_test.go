@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestFields(t *testing.T) {
+	assert := assert.New(t)
+	test := func(name string, contents string, expected map[string]string, expectedErr string) {
+		fields, err := ManifestFields(name, []byte(contents))
+		if expectedErr != "" {
+			assert.EqualError(err, expectedErr, name)
+			return
+		}
+		if !assert.NoError(err, name) {
+			return
+		}
+		actual := map[string]string{}
+		for _, field := range fields {
+			assert.Equal(field.Value, contents[field.Start:field.End], name)
+			actual[field.Name] = field.Value
+		}
+		assert.Equal(expected, actual, name)
+	}
+
+	test("VERSION", "\n  3.0.0  \n", map[string]string{"version": "3.0.0"}, "")
+	test("VERSION", "\n", nil, "VERSION has no version")
+	test("package.json", `{
+  "name": "foo",
+  "scripts": {"version": "echo"},
+  "files": ["version", {"version": "1.0.0"}],
+  "version": "3.0.0-rc.1",
+  "private": true
+}`, map[string]string{"version": "3.0.0-rc.1"}, "")
+	test("package.json", `{"dependencies": {"version": "1.0.0"}}`, nil, "package.json has no version")
+	test("package.json", `{"version": 3}`, nil, "unable to parse package.json: version is no string")
+	test("Chart.yaml", "apiVersion: v2\nname: foo\nversion: 3.0.0 # chart\nappVersion: \"v3.1.0\"\ndependencies:\n  - name: bar\n    version: 1.0.0\n", map[string]string{"version": "3.0.0", "appVersion": "v3.1.0"}, "")
+	test("sub/Chart.yaml", "appVersion: '3.1.0'\nversion: '3.0.0'\n", map[string]string{"version": "3.0.0", "appVersion": "3.1.0"}, "")
+	test("Cargo.toml", "[package]\nname = \"foo\"\nversion = \"3.0.0\"\n\n[dependencies.bar]\nversion = \"1.0.0\"\n", map[string]string{"version": "3.0.0"}, "")
+	test("Cargo.toml", "[workspace.package]\nversion = '3.0.0'\n", map[string]string{"version": "3.0.0"}, "")
+	test("Cargo.toml", "[package]\nversion.workspace = true\n", nil, "Cargo.toml has no version")
+	test("pyproject.toml", "[build-system]\nrequires = [\"hatchling\"]\n\n[project]\nname = \"foo\"\nversion = \"3.0.0\"\n", map[string]string{"version": "3.0.0"}, "")
+	test("pyproject.toml", "[tool.poetry]\nversion = \"3.0.0\"\n", map[string]string{"version": "3.0.0"}, "")
+	test("pom.xml", `<?xml version="1.0"?>
+<project>
+  <parent><groupId>foo</groupId><version>1.0.0</version></parent>
+  <artifactId>bar</artifactId>
+  <version>
+    3.0.0-SNAPSHOT
+  </version>
+  <dependencies><dependency><version>2.0.0</version></dependency></dependencies>
+</project>`, map[string]string{"version": "3.0.0-SNAPSHOT"}, "")
+	test("pom.xml", `<project><parent><version>1.0.0</version></parent></project>`, map[string]string{"version": "1.0.0"}, "")
+	test("setup.py", "", nil, "unsupported manifest setup.py")
+}
+
+func TestReadManifestVersion(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "package.json")
+	_, err := ReadManifestVersion(path)
+	assert.Error(err)
+	assert.NoError(os.WriteFile(path, []byte(`{"version": "3.0.0"}`), 0o644))
+	version, err := ReadManifestVersion(path)
+	assert.NoError(err)
+	assert.Equal("3.0.0", version)
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Notes ref holding version overrides by default
+	DefaultNotesRef = "refs/notes/versions"
+)
+
+// ExpandNotesRef expands short notes refs like git does (versions becomes
+// refs/notes/versions)
+func ExpandNotesRef(ref string) string {
+	switch {
+	case strings.HasPrefix(ref, "refs/notes/"):
+		return ref
+	case strings.HasPrefix(ref, "notes/"):
+		return "refs/" + ref
+	default:
+		return "refs/notes/" + ref
+	}
+}
+
+// GitVersionNotes returns the versions noted on commits under the notes ref
+// of the options by commit hash. Notes that are no semver are ignored.
+func GitVersionNotes(repo Repository, opts GitDescribeOptions) (map[string]string, error) {
+	result := map[string]string{}
+	if opts.NotesRef == "" {
+		return result, nil
+	}
+	notes, err := repo.Notes(ExpandNotesRef(opts.NotesRef))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read notes: %v", err)
+	}
+	for hash, note := range notes {
+		if version := strings.TrimSpace(note); semVerParseTag(version, opts.Loose) != nil {
+			result[hash] = version
+		}
+	}
+	return result, nil
+}
+
+// GitSetVersionNote notes the version on the commit, failing should there
+// already be another version noted on it or the version be noted on another
+// commit unless forced
+func GitSetVersionNote(repo Repository, version string, commit string, force bool, opts GitDescribeOptions) error {
+	if semVerParseTag(version, opts.Loose) == nil {
+		return fmt.Errorf("version %s is not a valid semantic version", version)
+	}
+	notes, err := GitVersionNotes(repo, opts)
+	if err != nil {
+		return err
+	}
+	for hash, existing := range notes {
+		if hash != commit && existing == version && !force {
+			return fmt.Errorf("version %s is already noted on commit %s", version, hash)
+		}
+	}
+	if existing, found := notes[commit]; found && existing != version && !force {
+		return fmt.Errorf("commit %s already has the version note %s", commit, existing)
+	}
+	return repo.SetNote(ExpandNotesRef(opts.NotesRef), commit, version)
+}
+
+// GitResolveBase returns the hash of the commit the base version returned by
+// GitDescribe stems from, which is either a tag or a version note. Should
+// several commits have the version noted, the first one reachable from HEAD
+// in the order of GitDescribe wins.
+func GitResolveBase(repo Repository, tagName string, opts GitDescribeOptions) (string, error) {
+	notes, err := GitVersionNotes(repo, opts)
+	if err != nil {
+		return "", err
+	}
+	candidates := map[string]bool{}
+	for hash, version := range notes {
+		if version == tagName {
+			candidates[hash] = true
+		}
+	}
+	if len(candidates) == 1 {
+		for hash := range candidates {
+			return hash, nil
+		}
+	} else if len(candidates) > 1 {
+		head, err := repo.Head()
+		if err != nil {
+			return "", fmt.Errorf("unable to find head: %v", err)
+		}
+		commits, err := repo.Log(head)
+		if err != nil {
+			return "", fmt.Errorf("unable to get log: %v", err)
+		}
+		for _, c := range commits {
+			if candidates[c.Hash] {
+				return c.Hash, nil
+			}
+		}
+	}
+	hash, err := repo.ResolveRevision(tagName)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve tag %s: %v", tagName, err)
+	}
+	return hash, nil
+}
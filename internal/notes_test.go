@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandNotesRef(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("refs/notes/versions", ExpandNotesRef("versions"))
+	assert.Equal("refs/notes/versions", ExpandNotesRef("notes/versions"))
+	assert.Equal("refs/notes/versions", ExpandNotesRef("refs/notes/versions"))
+}
+
+func TestRepositoryNotes(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@test.com")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@test.com")
+	dir, git := setUpCliRepository(t)
+	git("commit", "--allow-empty", "--quiet", "-m", "first")
+	commit1 := git("rev-parse", "HEAD")
+	git("commit", "--allow-empty", "--quiet", "-m", "second")
+	commit2 := git("rev-parse", "HEAD")
+	git("notes", "--ref=versions", "add", "-m", "v1.0.0", commit1)
+
+	goGitRepo, err := OpenRepository(dir, BackendGoGit)
+	assert.NoError(err)
+	cliRepo, err := OpenRepository(dir, BackendGit)
+	assert.NoError(err)
+	for _, repo := range []Repository{goGitRepo, cliRepo} {
+		notes, err := repo.Notes("refs/notes/other")
+		assert.NoError(err)
+		assert.Empty(notes)
+		notes, err = repo.Notes(DefaultNotesRef)
+		assert.NoError(err)
+		assert.Equal(map[string]string{commit1: "v1.0.0\n"}, notes)
+	}
+
+	// Notes written by go-git are read by git and vice versa
+	assert.NoError(goGitRepo.SetNote(DefaultNotesRef, commit2, "v2.0.0"))
+	assert.Equal("v2.0.0", git("notes", "--ref=versions", "show", commit2))
+	assert.Equal("v1.0.0", git("notes", "--ref=versions", "show", commit1))
+	assert.NoError(cliRepo.SetNote(DefaultNotesRef, commit1, "v1.1.0"))
+	notes, err := goGitRepo.Notes(DefaultNotesRef)
+	assert.NoError(err)
+	assert.Equal(map[string]string{commit1: "v1.1.0\n", commit2: "v2.0.0\n"}, notes)
+}
+
+func TestGitDescribeVersionNotes(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@test.com")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@test.com")
+	dir, git := setUpCliRepository(t)
+	git("commit", "--allow-empty", "--quiet", "-m", "first")
+	git("tag", "v1.0.0")
+	commit1 := git("rev-parse", "HEAD")
+	git("commit", "--allow-empty", "--quiet", "-m", "second")
+	commit2 := git("rev-parse", "HEAD")
+	git("commit", "--allow-empty", "--quiet", "-m", "third")
+	commit3 := git("rev-parse", "HEAD")
+	repo, err := OpenRepository(dir, BackendAuto)
+	assert.NoError(err)
+	opts := GitDescribeOptions{NotesRef: "versions"}
+	test := func(describe func(Repository, GitDescribeOptions) (*string, *int, *string, error), expectedTagName string, expectedCounter int) {
+		tagName, counter, _, err := describe(repo, opts)
+		assert.NoError(err)
+		assert.Equal(expectedTagName, *tagName)
+		assert.Equal(expectedCounter, *counter)
+	}
+
+	test(GitDescribe, "v1.0.0", 2)
+	test(GitDescribeCached, "v1.0.0", 2)
+
+	// Notes serve as base version
+	assert.NoError(GitSetVersionNote(repo, "v3.0.0", commit2, false, opts))
+	test(GitDescribe, "v3.0.0", 1)
+	test(GitDescribeCached, "v3.0.0", 1)
+	base, err := GitResolveBase(repo, "v3.0.0", opts)
+	assert.NoError(err)
+	assert.Equal(commit2, base)
+	base, err = GitResolveBase(repo, "v1.0.0", opts)
+	assert.NoError(err)
+	assert.Equal(commit1, base)
+
+	// Notes on HEAD override the version
+	assert.NoError(GitSetVersionNote(repo, "v3.0.1", commit3, false, opts))
+	test(GitDescribe, "v3.0.1", 0)
+	assert.EqualError(GitSetVersionNote(repo, "v3.0.2", commit3, false, opts), "commit "+commit3+" already has the version note v3.0.1")
+	assert.NoError(GitSetVersionNote(repo, "v3.0.2", commit3, true, opts))
+	test(GitDescribe, "v3.0.2", 0)
+	assert.EqualError(GitSetVersionNote(repo, "3.x", commit3, true, opts), "version 3.x is not a valid semantic version")
+
+	// Notes take precedence over tags on the same commit and are optional
+	assert.NoError(GitSetVersionNote(repo, "v0.9.0", commit1, false, opts))
+	git("checkout", "--quiet", commit1)
+	test(GitDescribe, "v0.9.0", 0)
+	opts.NotesRef = ""
+	test(GitDescribe, "v1.0.0", 0)
+
+	// Versions noted on several commits resolve to the one closest to HEAD
+	opts.NotesRef = "versions"
+	git("checkout", "--quiet", commit3)
+	assert.EqualError(GitSetVersionNote(repo, "v3.0.0", commit1, false, opts), "version v3.0.0 is already noted on commit "+commit2)
+	assert.NoError(GitSetVersionNote(repo, "v3.0.0", commit1, true, opts))
+	for i := 0; i < 10; i++ {
+		base, err = GitResolveBase(repo, "v3.0.0", opts)
+		assert.NoError(err)
+		assert.Equal(commit2, base)
+	}
+
+	// Notes on HEAD override the version even if an ancestor is higher
+	assert.NoError(GitSetVersionNote(repo, "v2.0.0", commit3, true, opts))
+	opts.Base = BaseHighest
+	test(GitDescribe, "v2.0.0", 0)
+	opts.Base = BaseNearest
+	test(GitDescribe, "v2.0.0", 0)
+}
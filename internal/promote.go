@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PromoteOptions ...
+type PromoteOptions struct {
+	// Message of the release tag, defaults to the message of the prerelease
+	// tag augmented by a Promoted-from trailer (lightweight prerelease tags
+	// are promoted to lightweight release tags)
+	Message string
+	// Coerce legacy tag names like v1.2 into semver
+	Loose bool
+	// Only determine the release tag without creating it
+	DryRun bool
+}
+
+// PromoteTag creates the release tag for the given prerelease tag (or the
+// highest prerelease tag on HEAD if empty) on the same commit. It fails
+// should there be a later prerelease of the same release or the release tag
+// already exist on another commit.
+func PromoteTag(repo Repository, tagName string, opts PromoteOptions) (*string, error) {
+	tags, err := repo.Tags(func(name string) bool {
+		return semVerParseTag(name, opts.Loose) != nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tags: %v", err)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	source, err := findPromotionSource(repo, tags, tagName, opts.Loose)
+	if err != nil {
+		return nil, err
+	}
+	prerelease := semVerParseTag(source.Name, opts.Loose)
+	if len(prerelease.Prerelease) == 0 {
+		return nil, fmt.Errorf("tag %s is not a prerelease", source.Name)
+	}
+	release := SemVer{
+		Prefix: prerelease.Prefix,
+		Major:  prerelease.Major,
+		Minor:  prerelease.Minor,
+		Patch:  prerelease.Patch,
+	}
+	releaseName := release.String()
+
+	for _, tag := range tags {
+		version := semVerParseTag(tag.Name, opts.Loose)
+		if version.Compare(release) == 0 {
+			if tag.Commit != source.Commit {
+				return nil, fmt.Errorf("release %s already exists as tag %s on another commit", releaseName, tag.Name)
+			}
+			// Already promoted
+			return &tag.Name, nil
+		}
+		if version.Major == release.Major && version.Minor == release.Minor && version.Patch == release.Patch && version.Compare(*prerelease) > 0 {
+			return nil, fmt.Errorf("tag %s is superseded by the later prerelease %s", source.Name, tag.Name)
+		}
+	}
+
+	message := opts.Message
+	if message == "" {
+		sourceMessage, err := repo.TagMessage(*source)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read message of tag %s: %v", source.Name, err)
+		}
+		if sourceMessage != "" {
+			message = strings.TrimRight(sourceMessage, "\n") + "\n\nPromoted-from: " + source.Name + "\n"
+		}
+	}
+	if !opts.DryRun {
+		if err := repo.CreateTag(releaseName, source.Commit, message); err != nil {
+			return nil, fmt.Errorf("unable to create tag %s: %v", releaseName, err)
+		}
+	}
+	return &releaseName, nil
+}
+
+func findPromotionSource(repo Repository, tags []Tag, tagName string, loose bool) (*Tag, error) {
+	if tagName != "" {
+		for i := range tags {
+			if tags[i].Name == tagName {
+				return &tags[i], nil
+			}
+		}
+		return nil, fmt.Errorf("semver tag %s not found", tagName)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("unable to find head: %v", err)
+	}
+	var result *Tag
+	var highest *SemVer
+	for i := range tags {
+		version := semVerParseTag(tags[i].Name, loose)
+		if tags[i].Commit != head || len(version.Prerelease) == 0 {
+			continue
+		}
+		if highest == nil || version.Compare(*highest) > 0 {
+			result = &tags[i]
+			highest = version
+		}
+	}
+	if result == nil {
+		return nil, fmt.Errorf("HEAD has no prerelease tag")
+	}
+	return result, nil
+}
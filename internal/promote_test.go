@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromoteTag(t *testing.T) {
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@test.com")
+	for _, backend := range []string{BackendGit, BackendGoGit} {
+		assert := assert.New(t)
+		dir, git := setUpCliRepository(t)
+		git("commit", "--allow-empty", "--quiet", "-m", "first")
+		git("tag", "-a", "-m", "Version 2.1.0-rc.2\n\nRelease notes", "v2.1.0-rc.2")
+		commit1 := git("rev-parse", "HEAD")
+		git("commit", "--allow-empty", "--quiet", "-m", "second")
+		git("tag", "-a", "-m", "Version 2.1.0-rc.3", "v2.1.0-rc.3")
+		git("tag", "v2.2.0-beta.1")
+		commit2 := git("rev-parse", "HEAD")
+		repo, err := OpenRepository(dir, backend)
+		assert.NoError(err)
+
+		_, err = PromoteTag(repo, "v2.1.0-rc.2", PromoteOptions{})
+		assert.EqualError(err, "tag v2.1.0-rc.2 is superseded by the later prerelease v2.1.0-rc.3", backend)
+		_, err = PromoteTag(repo, "v2.0.0", PromoteOptions{})
+		assert.EqualError(err, "semver tag v2.0.0 not found", backend)
+
+		// HEAD's highest prerelease tag is used by default
+		result, err := PromoteTag(repo, "", PromoteOptions{DryRun: true})
+		assert.NoError(err)
+		assert.Equal("v2.2.0", *result)
+		assert.Equal(commit2, git("rev-parse", "HEAD"))
+		assert.NotContains(git("tag", "--list"), "v2.2.0\n")
+
+		result, err = PromoteTag(repo, "v2.1.0-rc.3", PromoteOptions{})
+		assert.NoError(err)
+		assert.Equal("v2.1.0", *result)
+		assert.Equal(commit2, git("rev-parse", "v2.1.0^{commit}"))
+		assert.Equal("tag", git("cat-file", "-t", "v2.1.0"))
+		assert.Equal("Version 2.1.0-rc.3\n\nPromoted-from: v2.1.0-rc.3", git("tag", "--list", "--format=%(contents)", "v2.1.0"))
+
+		// Promoting again is a no-op
+		result, err = PromoteTag(repo, "v2.1.0-rc.3", PromoteOptions{})
+		assert.NoError(err)
+		assert.Equal("v2.1.0", *result)
+
+		// Lightweight prereleases are promoted to lightweight releases
+		result, err = PromoteTag(repo, "v2.2.0-beta.1", PromoteOptions{})
+		assert.NoError(err)
+		assert.Equal("v2.2.0", *result)
+		assert.Equal("commit", git("cat-file", "-t", "v2.2.0"))
+
+		git("tag", "v2.3.0-rc.1", commit1)
+		git("tag", "v2.3.0", commit2)
+		_, err = PromoteTag(repo, "v2.3.0-rc.1", PromoteOptions{Message: "Release"})
+		assert.EqualError(err, "release v2.3.0 already exists as tag v2.3.0 on another commit", backend)
+		_, err = PromoteTag(repo, "v2.3.0", PromoteOptions{})
+		assert.EqualError(err, "tag v2.3.0 is not a prerelease", backend)
+	}
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// Use the git CLI if the repository requires it, go-git otherwise
+	BackendAuto = "auto"
+	// Use the pure go git implementation
+	BackendGoGit = "go-git"
+	// Shell out to the local git binary
+	BackendGit = "git"
+)
+
+const (
+	ObjectFormatSHA1   = "sha1"
+	ObjectFormatSHA256 = "sha256"
+)
+
+// Repository abstracts the access to a git repository
+type Repository interface {
+	// GitDir returns the path of the git directory (empty if unknown)
+	GitDir() string
+	// Head returns the hash of the commit HEAD points to
+	Head() (string, error)
+	// ResolveRevision returns the hash of the commit the revision (like a
+	// tag, branch or abbreviated hash) points to
+	ResolveRevision(rev string) (string, error)
+	// Commit returns the commit with the given hash
+	Commit(hash string) (*Commit, error)
+	// Log returns all commits reachable from the given commit ordered by
+	// committer time (newest first). The commit messages are not populated.
+	Log(from string) ([]Commit, error)
+	// LogMessages is like Log, but populates the commit messages
+	LogMessages(from string) ([]Commit, error)
+	// TagNames returns the names of all tags
+	TagNames() ([]string, error)
+	// Tags returns all tags whose name is accepted by the filter. Tags are
+	// only resolved after being filtered.
+	Tags(filter func(name string) bool) ([]Tag, error)
+	// TagDate returns the tagger date of annotated tags and the committer
+	// date of the tagged commit for lightweight tags
+	TagDate(tag Tag) (time.Time, error)
+	// TagMessage returns the message (without signature) of annotated tags
+	// and an empty string for lightweight tags
+	TagMessage(tag Tag) (string, error)
+	// CreateTag creates an annotated tag if a message is given and a
+	// lightweight tag otherwise
+	CreateTag(name string, commit string, message string) error
+	// Notes returns the notes stored under the given notes ref (like
+	// refs/notes/versions) by the hash of the object they annotate
+	Notes(ref string) (map[string]string, error)
+	// SetNote adds or replaces the note of the given object
+	SetNote(ref string, hash string, message string) error
+	// Abbreviate returns the shortest unique prefix of the given hash with
+	// at least the given length
+	Abbreviate(hash string, minLength int) (string, error)
+	// Shallow returns the hashes of the commits whose parents are missing
+	// because of a shallow clone
+	Shallow() ([]string, error)
+	// Dirty returns whether the worktree has uncommitted changes to tracked
+	// files (always false without worktree)
+	Dirty() (bool, error)
+}
+
+// Commit ...
+type Commit struct {
+	Hash          string
+	Parents       []string
+	AuthorWhen    time.Time
+	CommitterWhen time.Time
+	Message       string
+}
+
+// Tag ...
+type Tag struct {
+	Name string
+	// Hash of the tag object for annotated tags, of the commit otherwise
+	Hash string
+	// Hash of the commit the tag points to
+	Commit    string
+	Annotated bool
+}
+
+// OpenRepository opens the repository found at dir with the given backend
+func OpenRepository(dir string, backend string) (Repository, error) {
+	gitDir, workTree, err := FindRepository(dir)
+	if err != nil {
+		return nil, err
+	}
+	switch backend {
+	case BackendGoGit:
+		return openGoGitRepository(gitDir, workTree)
+	case BackendGit:
+		return openCliRepository(gitDir, workTree)
+	case "", BackendAuto:
+		if _, err := exec.LookPath("git"); err != nil {
+			return openGoGitRepository(gitDir, workTree)
+		}
+		if requiresCli, err := requiresCliBackend(gitDir); err != nil || requiresCli {
+			return openCliRepository(gitDir, workTree)
+		}
+		repo, err := openGoGitRepository(gitDir, workTree)
+		if err != nil {
+			return openCliRepository(gitDir, workTree)
+		}
+		return repo, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %s", backend)
+	}
+}
+
+// requiresCliBackend checks the repository configuration for features that
+// go-git does not support, like repository extensions (partial clones,
+// worktree config, object formats) or promisor remotes
+func requiresCliBackend(gitDir string) (bool, error) {
+	config, err := readGitConfig(gitDir)
+	if err != nil {
+		return false, err
+	}
+	for key := range config {
+		if strings.HasPrefix(key, "extensions.") || key == "remote.promisor" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// readObjectFormat returns the hash algorithm used by the repository
+func readObjectFormat(gitDir string) (string, error) {
+	config, err := readGitConfig(gitDir)
+	if err != nil {
+		return "", err
+	}
+	if format, found := config["extensions.objectformat"]; found && format != "" {
+		return strings.ToLower(format), nil
+	}
+	return ObjectFormatSHA1, nil
+}
+
+// readGitConfig reads the repository configuration as flat map from
+// lowercased "section.key" (subsections are dropped) to value
+func readGitConfig(gitDir string) (map[string]string, error) {
+	configDir := gitDir
+	if enableCommonDir, err := shouldEnableCommondDir(gitDir); err != nil {
+		return nil, err
+	} else if enableCommonDir {
+		if configDir, err = readCommonDir(gitDir); err != nil {
+			return nil, err
+		}
+	}
+	config := map[string]string{}
+	contents, err := os.ReadFile(filepath.Join(configDir, "config"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return nil, err
+	}
+	section := ""
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			section = ""
+			if fields := strings.Fields(strings.Trim(line, "[]")); len(fields) > 0 {
+				section = strings.ToLower(fields[0])
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := ""
+		if len(parts) == 2 {
+			value = strings.TrimSpace(parts[1])
+		}
+		config[section+"."+key] = value
+	}
+	return config, nil
+}
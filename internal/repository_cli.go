@@ -0,0 +1,318 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Separates fields in the output of git log and git for-each-ref
+	cliFieldSeparator = "\x1f"
+	cliCommitFormat   = "%H%x1f%P%x1f%at%x1f%ct%x1f%B"
+)
+
+type cliRepository struct {
+	gitDir   string
+	workTree string
+	// Tag dates already known from listing the tags
+	tagDates map[string]time.Time
+}
+
+func openCliRepository(gitDir string, workTree string) (Repository, error) {
+	repo := &cliRepository{gitDir: gitDir, workTree: workTree, tagDates: map[string]time.Time{}}
+	if _, err := repo.git("rev-parse", "--git-dir"); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *cliRepository) git(args ...string) (string, error) {
+	return r.gitWithInput("", args...)
+}
+
+func (r *cliRepository) gitWithInput(input string, args ...string) (string, error) {
+	globalArgs := []string{"--git-dir", r.gitDir}
+	if r.workTree != "" {
+		globalArgs = append(globalArgs, "--work-tree", r.workTree)
+	}
+	cmd := exec.Command("git", append(globalArgs, args...)...)
+	// Do not let the environment override the discovered repository
+	cmd.Env = []string{"LC_ALL=C"}
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "GIT_DIR=") && !strings.HasPrefix(env, "GIT_WORK_TREE=") && !strings.HasPrefix(env, "LC_ALL=") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (r *cliRepository) GitDir() string {
+	return r.gitDir
+}
+
+func (r *cliRepository) Head() (string, error) {
+	out, err := r.git("rev-parse", "--verify", "HEAD^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (r *cliRepository) ResolveRevision(rev string) (string, error) {
+	out, err := r.git("rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (r *cliRepository) Commit(hash string) (*Commit, error) {
+	out, err := r.git("log", "-1", "-z", "--format="+cliCommitFormat, hash)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := parseCliCommits(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) != 1 {
+		return nil, fmt.Errorf("commit %s not found", hash)
+	}
+	return &commits[0], nil
+}
+
+func (r *cliRepository) Log(from string) ([]Commit, error) {
+	out, err := r.git("log", "-z", "--date-order", "--format="+strings.TrimSuffix(cliCommitFormat, "%B"), from)
+	if err != nil {
+		return nil, err
+	}
+	return parseCliCommits(out)
+}
+
+func (r *cliRepository) LogMessages(from string) ([]Commit, error) {
+	out, err := r.git("log", "-z", "--date-order", "--format="+cliCommitFormat, from)
+	if err != nil {
+		return nil, err
+	}
+	return parseCliCommits(out)
+}
+
+func (r *cliRepository) TagNames() ([]string, error) {
+	out, err := r.git("for-each-ref", "--format=%(refname:strip=2)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+func (r *cliRepository) Tags(filter func(name string) bool) ([]Tag, error) {
+	fields := []string{"%(refname:strip=2)", "%(objectname)", "%(objecttype)", "%(*objectname)", "%(*objecttype)", "%(taggerdate:unix)", "%(committerdate:unix)"}
+	out, err := r.git("for-each-ref", "--format="+strings.Join(fields, "%1f"), "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+	result := []Tag{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.Split(line, cliFieldSeparator)
+		if len(parts) != len(fields) || !filter(parts[0]) {
+			continue
+		}
+		tag := Tag{Name: parts[0], Hash: parts[1], Commit: parts[1]}
+		date := parts[6]
+		if parts[2] == "tag" {
+			tag.Annotated = true
+			tag.Commit = parts[3]
+			date = parts[5]
+			if parts[4] != "commit" {
+				// Nested tags need to be peeled until reaching the commit
+				out, err := r.git("rev-parse", "--verify", parts[1]+"^{commit}")
+				if err != nil {
+					continue
+				}
+				tag.Commit = strings.TrimSpace(out)
+			}
+		} else if parts[2] != "commit" {
+			continue
+		}
+		if when, err := parseUnixTimestamp(date); err == nil {
+			r.tagDates[tag.Hash] = when
+		}
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+func (r *cliRepository) TagDate(tag Tag) (time.Time, error) {
+	if when, found := r.tagDates[tag.Hash]; found {
+		return when, nil
+	}
+	format := "%(committerdate:unix)"
+	if tag.Annotated {
+		format = "%(taggerdate:unix)"
+	}
+	out, err := r.git("for-each-ref", "--format="+format, "refs/tags/"+tag.Name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseUnixTimestamp(out)
+}
+
+func (r *cliRepository) TagMessage(tag Tag) (string, error) {
+	if !tag.Annotated {
+		return "", nil
+	}
+	out, err := r.git("cat-file", "tag", tag.Hash)
+	if err != nil {
+		return "", err
+	}
+	// The message follows the headers after an empty line
+	message := ""
+	if i := strings.Index(out, "\n\n"); i >= 0 {
+		message = out[i+2:]
+	}
+	for _, marker := range []string{"-----BEGIN PGP SIGNATURE-----", "-----BEGIN SSH SIGNATURE-----"} {
+		if i := strings.Index(message, marker); i >= 0 {
+			message = message[:i]
+		}
+	}
+	return message, nil
+}
+
+func (r *cliRepository) CreateTag(name string, commit string, message string) error {
+	args := []string{"tag"}
+	if message != "" {
+		args = append(args, "-a", "-m", message)
+	}
+	_, err := r.git(append(args, "--", name, commit)...)
+	return err
+}
+
+func (r *cliRepository) Notes(ref string) (map[string]string, error) {
+	out, err := r.git("notes", "--ref="+ref, "list")
+	if err != nil {
+		return nil, err
+	}
+	objects := []string{}
+	blobs := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			blobs = append(blobs, fields[0])
+			objects = append(objects, fields[1])
+		}
+	}
+	result := map[string]string{}
+	if len(blobs) == 0 {
+		return result, nil
+	}
+	// Read all notes with a single process, each as "<hash> blob <size>\n<contents>\n"
+	out, err = r.gitWithInput(strings.Join(blobs, "\n")+"\n", "cat-file", "--batch")
+	if err != nil {
+		return nil, err
+	}
+	for i := range blobs {
+		header, rest, _ := strings.Cut(out, "\n")
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git cat-file output: %q", header)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil || size+1 > len(rest) {
+			return nil, fmt.Errorf("unexpected git cat-file output: %q", header)
+		}
+		result[objects[i]] = rest[:size]
+		out = rest[size+1:]
+	}
+	return result, nil
+}
+
+func (r *cliRepository) SetNote(ref string, hash string, message string) error {
+	_, err := r.git("notes", "--ref="+ref, "add", "-f", "-m", message, hash)
+	return err
+}
+
+func (r *cliRepository) Abbreviate(hash string, minLength int) (string, error) {
+	out, err := r.git("rev-parse", "--short="+strconv.Itoa(minLength), hash)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (r *cliRepository) Shallow() ([]string, error) {
+	out, err := r.git("rev-parse", "--git-path", "shallow")
+	if err != nil {
+		return nil, err
+	}
+	contents, err := os.ReadFile(strings.TrimSpace(out))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	return strings.Fields(string(contents)), nil
+}
+
+func (r *cliRepository) Dirty() (bool, error) {
+	if r.workTree == "" {
+		return false, nil
+	}
+	out, err := r.git("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+func parseCliCommits(out string) ([]Commit, error) {
+	result := []Commit{}
+	for _, entry := range strings.Split(out, "\x00") {
+		entry = strings.TrimPrefix(entry, "\n")
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, cliFieldSeparator, 5)
+		if len(parts) < 4 {
+			return nil, fmt.Errorf("unexpected git log output: %q", entry)
+		}
+		authorWhen, err := parseUnixTimestamp(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		committerWhen, err := parseUnixTimestamp(parts[3])
+		if err != nil {
+			return nil, err
+		}
+		commit := Commit{
+			Hash:          parts[0],
+			Parents:       strings.Fields(parts[1]),
+			AuthorWhen:    authorWhen,
+			CommitterWhen: committerWhen,
+		}
+		if len(parts) == 5 {
+			commit.Message = parts[4]
+		}
+		result = append(result, commit)
+	}
+	return result, nil
+}
+
+func parseUnixTimestamp(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid unix timestamp %s", value)
+	}
+	return time.Unix(seconds, 0), nil
+}
@@ -0,0 +1,300 @@
+package internal
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+)
+
+func setUpCliRepository(t testing.TB, initArgs ...string) (string, func(args ...string) string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+	dir, err := ioutil.TempDir("", "example")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for _, name := range []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_CEILING_DIRECTORIES"} {
+		// Restore the original value after the test, but unset it meanwhile
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	date := 1577836800
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_CONFIG_NOSYSTEM=1",
+			"HOME="+dir,
+			"GIT_AUTHOR_NAME=Test",
+			"GIT_AUTHOR_EMAIL=test@test.com",
+			"GIT_COMMITTER_NAME=Test",
+			"GIT_COMMITTER_EMAIL=test@test.com",
+			"GIT_AUTHOR_DATE=@"+strconv.Itoa(date)+" +0000",
+			"GIT_COMMITTER_DATE=@"+strconv.Itoa(date)+" +0000",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s failed: %v: %s", strings.Join(args, " "), err, out)
+		}
+		if args[0] == "commit" || args[0] == "merge" || args[0] == "tag" {
+			date += 60
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git(append([]string{"init", "--quiet", "--initial-branch", "main"}, initArgs...)...)
+	return dir, git
+}
+
+func TestCliRepository(t *testing.T) {
+	assert := assert.New(t)
+	dir, git := setUpCliRepository(t)
+
+	git("commit", "--allow-empty", "--quiet", "-m", "first")
+	commit1 := git("rev-parse", "HEAD")
+	git("tag", "v1.0.0")
+	git("commit", "--allow-empty", "--quiet", "-m", "second\n\nwith body")
+	commit2 := git("rev-parse", "HEAD")
+	git("tag", "-a", "-m", "Version 1.1.0", "v1.1.0")
+	git("tag", "latest")
+	git("checkout", "--quiet", "-b", "feature", commit1)
+	git("commit", "--allow-empty", "--quiet", "-m", "third")
+	git("checkout", "--quiet", "main")
+	git("merge", "--no-ff", "--quiet", "-m", "merge", "feature")
+	head := git("rev-parse", "HEAD")
+
+	cli, err := OpenRepository(dir, BackendGit)
+	assert.NoError(err)
+	goGit, err := OpenRepository(dir, BackendGoGit)
+	assert.NoError(err)
+
+	for _, repo := range []Repository{cli, goGit} {
+		actualHead, err := repo.Head()
+		assert.NoError(err)
+		assert.Equal(head, actualHead)
+
+		c, err := repo.Commit(commit2)
+		assert.NoError(err)
+		assert.Equal(commit2, c.Hash)
+		assert.Equal([]string{commit1}, c.Parents)
+		assert.Equal(int64(1577836920), c.CommitterWhen.Unix())
+		assert.Equal("second\n\nwith body\n", c.Message)
+
+		commits, err := repo.Log(head)
+		assert.NoError(err)
+		assert.Len(commits, 4)
+		assert.Equal(head, commits[0].Hash)
+		assert.Len(commits[0].Parents, 2)
+		withMessages, err := repo.LogMessages(head)
+		assert.NoError(err)
+		assert.Len(withMessages, 4)
+		for i, c := range withMessages {
+			assert.Equal(commits[i].Hash, c.Hash)
+			if c.Hash == commit2 {
+				assert.Equal("second\n\nwith body\n", c.Message)
+			}
+		}
+
+		tagNames, err := repo.TagNames()
+		assert.NoError(err)
+		assert.ElementsMatch([]string{"latest", "v1.0.0", "v1.1.0"}, tagNames)
+
+		tags, err := repo.Tags(func(name string) bool { return name != "latest" })
+		assert.NoError(err)
+		if assert.Len(tags, 2) {
+			assert.Equal(Tag{Name: "v1.0.0", Hash: commit1, Commit: commit1}, tags[0])
+			assert.Equal("v1.1.0", tags[1].Name)
+			assert.NotEqual(commit2, tags[1].Hash)
+			assert.Equal(commit2, tags[1].Commit)
+			assert.True(tags[1].Annotated)
+			when, err := repo.TagDate(tags[1])
+			assert.NoError(err)
+			assert.Equal(int64(1577836980), when.Unix())
+			when, err = repo.TagDate(tags[0])
+			assert.NoError(err)
+			assert.Equal(int64(1577836800), when.Unix())
+		}
+
+		shallow, err := repo.Shallow()
+		assert.NoError(err)
+		assert.Empty(shallow)
+
+		abbreviated, err := repo.Abbreviate(head, 7)
+		assert.NoError(err)
+		assert.Equal(head[0:7], abbreviated)
+		abbreviated, err = repo.Abbreviate(head, 12)
+		assert.NoError(err)
+		assert.Equal(head[0:12], abbreviated)
+
+		tagName, counter, headHash, err := GitDescribe(repo, GitDescribeOptions{})
+		assert.NoError(err)
+		assert.Equal("v1.1.0", *tagName)
+		assert.Equal(1, *counter)
+		assert.Equal(head, *headHash)
+	}
+}
+
+func TestCliRepositoryShallow(t *testing.T) {
+	assert := assert.New(t)
+	dir, git := setUpCliRepository(t)
+	git("commit", "--allow-empty", "--quiet", "-m", "first")
+	git("tag", "v1.0.0")
+	git("commit", "--allow-empty", "--quiet", "-m", "second")
+	git("commit", "--allow-empty", "--quiet", "-m", "third")
+
+	cloneDir := filepath.Join(dir, "clone")
+	git("clone", "--quiet", "--depth", "1", "--no-tags", "file://"+dir, cloneDir)
+	for _, backend := range []string{BackendGit, BackendGoGit} {
+		repo, err := OpenRepository(cloneDir, backend)
+		assert.NoError(err)
+		shallow, err := repo.Shallow()
+		assert.NoError(err)
+		assert.Len(shallow, 1)
+		_, _, _, err = GitDescribe(repo, GitDescribeOptions{})
+		assert.Equal(IncompleteHistoryError{Shallow: true, NoTags: true}, err, backend)
+		tagName, counter, _, err := GitDescribe(repo, GitDescribeOptions{AllowShallow: true, AllowNoTags: true})
+		assert.NoError(err)
+		assert.Equal("", *tagName)
+		assert.Equal(1, *counter)
+	}
+}
+
+func TestCliRepositoryDirty(t *testing.T) {
+	assert := assert.New(t)
+	dir, git := setUpCliRepository(t)
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("first"), 0o644)
+	git("add", "file.txt")
+	git("commit", "--quiet", "-m", "first")
+	test := func(expected bool) {
+		for _, backend := range []string{BackendGit, BackendGoGit} {
+			repo, err := OpenRepository(dir, backend)
+			assert.NoError(err)
+			dirty, err := repo.Dirty()
+			assert.NoError(err)
+			assert.Equal(expected, dirty, backend)
+		}
+	}
+
+	test(false)
+	os.WriteFile(filepath.Join(dir, "untracked.txt"), []byte("untracked"), 0o644)
+	test(false)
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("changed"), 0o644)
+	test(true)
+	git("add", "file.txt")
+	test(true)
+}
+
+func TestRequiresCliBackend(t *testing.T) {
+	assert := assert.New(t)
+	test := func(config string, expected bool) {
+		gitDir, _ := ioutil.TempDir("", "example")
+		defer os.RemoveAll(gitDir)
+		os.WriteFile(filepath.Join(gitDir, "config"), []byte(config), 0666)
+		actual, err := requiresCliBackend(gitDir)
+		assert.NoError(err)
+		assert.Equal(expected, actual, config)
+	}
+
+	test("[core]\n\trepositoryformatversion = 0\n", false)
+	test("[core]\n\trepositoryformatversion = 1\n[extensions]\n\tpartialClone = origin\n", true)
+	test("[core]\n\trepositoryformatversion = 1\n[extensions]\n\t# nothing\n", false)
+	test("[remote \"origin\"]\n\turl = https://example.com\n\tpromisor = true\n", true)
+	test("[remote \"origin\"]\n\turl = https://example.com\n", false)
+}
+
+func TestSha256Repository(t *testing.T) {
+	assert := assert.New(t)
+	dir, git := setUpCliRepository(t, "--object-format=sha256")
+	git("commit", "--allow-empty", "--quiet", "-m", "first")
+	commit1 := git("rev-parse", "HEAD")
+	git("tag", "v1.0.0")
+	git("commit", "--allow-empty", "--quiet", "-m", "second")
+	git("tag", "-a", "-m", "Version 1.1.0-rc.1", "v1.1.0-rc.1")
+	commit2 := git("rev-parse", "HEAD")
+	git("commit", "--allow-empty", "--quiet", "-m", "third")
+	commit3 := git("rev-parse", "HEAD")
+	assert.Len(commit3, 64)
+
+	objectFormat, err := readObjectFormat(filepath.Join(dir, GitDirName))
+	assert.NoError(err)
+	assert.Equal(ObjectFormatSHA256, objectFormat)
+
+	_, err = OpenRepository(dir, BackendGoGit)
+	assert.Error(err)
+
+	repo, err := OpenRepository(dir, BackendAuto)
+	assert.NoError(err)
+	assert.IsType(&cliRepository{}, repo)
+
+	tags, err := GitTagMap(repo, GitDescribeOptions{})
+	assert.NoError(err)
+	assert.Equal(map[string]string{commit1: "v1.0.0", commit2: "v1.1.0-rc.1"}, *tags)
+
+	tagName, counter, headHash, err := GitDescribe(repo, GitDescribeOptions{})
+	assert.NoError(err)
+	assert.Equal("v1.1.0-rc.1", *tagName)
+	assert.Equal(1, *counter)
+	assert.Equal(commit3, *headHash)
+
+	result, err := GenerateVersion(*tagName, *counter, *headHash, time.Now(), GenerateVersionOptions{PrereleasePrefix: "dev"})
+	assert.NoError(err)
+	assert.Equal("v1.1.0-rc.1.dev.1.g"+commit3[0:7], *result)
+}
+
+func TestAbbreviate(t *testing.T) {
+	assert := assert.New(t)
+	dir, git := setUpCliRepository(t)
+	cli, err := OpenRepository(dir, BackendGit)
+	assert.NoError(err)
+	goGit, err := OpenRepository(dir, BackendGoGit)
+	assert.NoError(err)
+
+	// Write enough objects to get ambiguous short prefixes
+	hashes := []string{}
+	for i := 0; i < 2000; i++ {
+		obj := goGit.(*goGitRepository).repo.Storer.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, _ := obj.Writer()
+		w.Write([]byte(strconv.Itoa(i)))
+		w.Close()
+		hash, err := goGit.(*goGitRepository).repo.Storer.SetEncodedObject(obj)
+		assert.NoError(err)
+		hashes = append(hashes, hash.String())
+	}
+	git("repack", "-a", "-d", "-k", "--quiet")
+	git("prune-packed")
+	goGit, err = OpenRepository(dir, BackendGoGit)
+	assert.NoError(err)
+
+	ambiguous := 0
+	for _, hash := range hashes[0:200] {
+		expected, err := cli.Abbreviate(hash, 4)
+		assert.NoError(err)
+		actual, err := goGit.Abbreviate(hash, 4)
+		assert.NoError(err)
+		assert.Equal(expected, actual)
+		if len(expected) > 4 {
+			ambiguous++
+		}
+	}
+	assert.Greater(ambiguous, 0)
+
+	for _, minLength := range []int{1, 50} {
+		expected, err := cli.Abbreviate(hashes[0], minLength)
+		assert.NoError(err)
+		actual, err := goGit.Abbreviate(hashes[0], minLength)
+		assert.NoError(err)
+		assert.Equal(expected, actual, minLength)
+	}
+	abbreviated, _ := goGit.Abbreviate(hashes[0], 50)
+	assert.Equal(hashes[0], abbreviated)
+	abbreviated, _ = goGit.Abbreviate(hashes[0], 1)
+	assert.GreaterOrEqual(len(abbreviated), 4)
+}
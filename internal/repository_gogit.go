@@ -0,0 +1,655 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/filesystem/dotgit"
+)
+
+type goGitRepository struct {
+	repo   *git.Repository
+	gitDir string
+}
+
+// NewGoGitRepository wraps an already opened go-git repository
+func NewGoGitRepository(repo *git.Repository) Repository {
+	gitDir := ""
+	if storage, ok := repo.Storer.(*filesystem.Storage); ok {
+		gitDir = storage.Filesystem().Root()
+	}
+	return &goGitRepository{repo: repo, gitDir: gitDir}
+}
+
+func openGoGitRepository(gitDir string, workTree string) (Repository, error) {
+	objectFormat, err := readObjectFormat(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	if objectFormat != ObjectFormatSHA1 {
+		return nil, fmt.Errorf("go-git does not support the %s object format (use the git backend instead)", objectFormat)
+	}
+	storage, err := openGoGitStorage(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	var workTreeFs billy.Filesystem
+	if workTree != "" {
+		workTreeFs = osfs.New(workTree)
+	}
+	repo, err := git.Open(storage, workTreeFs)
+	if err != nil {
+		return nil, err
+	}
+	return &goGitRepository{repo: repo, gitDir: gitDir}, nil
+}
+
+func openGoGitStorage(gitDir string) (*filesystem.Storage, error) {
+	enableCommonDir, err := shouldEnableCommondDir(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	var repoFs billy.Filesystem = osfs.New(gitDir)
+	if enableCommonDir {
+		commonDir, err := readCommonDir(gitDir)
+		if err != nil {
+			return nil, err
+		}
+		repoFs = dotgit.NewRepositoryFilesystem(repoFs, osfs.New(commonDir))
+	}
+	return filesystem.NewStorage(repoFs, cache.NewObjectLRUDefault()), nil
+}
+
+func (r *goGitRepository) GitDir() string {
+	return r.gitDir
+}
+
+func (r *goGitRepository) Head() (string, error) {
+	head, err := r.repo.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
+
+func (r *goGitRepository) ResolveRevision(rev string) (string, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %s: %v", rev, err)
+	}
+	// Peel annotated tags
+	for {
+		obj, err := r.repo.Storer.EncodedObject(plumbing.AnyObject, *hash)
+		if err != nil {
+			return "", err
+		}
+		if obj.Type() != plumbing.TagObject {
+			return hash.String(), nil
+		}
+		t, err := object.DecodeTag(r.repo.Storer, obj)
+		if err != nil {
+			return "", err
+		}
+		hash = &t.Target
+	}
+}
+
+func (r *goGitRepository) Commit(hash string) (*Commit, error) {
+	c, err := r.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, err
+	}
+	result := convertGoGitCommit(c)
+	result.Message = c.Message
+	return &result, nil
+}
+
+func (r *goGitRepository) Log(from string) ([]Commit, error) {
+	return r.log(from, false)
+}
+
+func (r *goGitRepository) LogMessages(from string) ([]Commit, error) {
+	return r.log(from, true)
+}
+
+func (r *goGitRepository) log(from string, messages bool) ([]Commit, error) {
+	head, err := r.repo.CommitObject(plumbing.NewHash(from))
+	if err != nil {
+		return nil, err
+	}
+	// Skip the missing parents of shallow commits, the log would end early otherwise
+	missing := map[plumbing.Hash]bool{}
+	shallow, err := r.repo.Storer.Shallow()
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range shallow {
+		c, err := r.repo.CommitObject(hash)
+		if err != nil {
+			continue
+		}
+		for _, p := range c.ParentHashes {
+			if _, err := r.repo.CommitObject(p); errors.Is(err, plumbing.ErrObjectNotFound) {
+				missing[p] = true
+			}
+		}
+	}
+	result := []Commit{}
+	err = object.NewCommitIterCTime(head, missing, nil).ForEach(func(c *object.Commit) error {
+		commit := convertGoGitCommit(c)
+		if messages {
+			commit.Message = c.Message
+		}
+		result = append(result, commit)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *goGitRepository) TagNames() ([]string, error) {
+	iter, err := r.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		result = append(result, ref.Name().Short())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *goGitRepository) Tags(filter func(name string) bool) ([]Tag, error) {
+	iter, err := r.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	packedRefs, err := r.readPackedRefs()
+	if err != nil {
+		return nil, err
+	}
+	result := []Tag{}
+	unresolved := []int{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if !filter(ref.Name().Short()) {
+			return nil
+		}
+		tag := Tag{
+			Name:   ref.Name().Short(),
+			Hash:   ref.Hash().String(),
+			Commit: ref.Hash().String(),
+		}
+		// Use the peeled value from packed-refs unless the ref has been
+		// overwritten by a loose ref in the meantime
+		if packed, found := packedRefs[ref.Name().String()]; found && packed.Hash == tag.Hash {
+			if packed.Peeled != "" {
+				tag.Annotated = true
+				tag.Commit = packed.Peeled
+			}
+		} else {
+			unresolved = append(unresolved, len(result))
+		}
+		result = append(result, tag)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.resolveTags(result, unresolved); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolveTags peels the given tags concurrently with a bounded number of
+// workers. Each worker uses its own storage since go-git storages are not
+// safe for concurrent use.
+func (r *goGitRepository) resolveTags(tags []Tag, indices []int) error {
+	if len(indices) == 0 {
+		return nil
+	}
+	workers := runtime.NumCPU()
+	if workers > len(indices) {
+		workers = len(indices)
+	}
+	if r.gitDir == "" || workers < 2 {
+		for _, i := range indices {
+			if err := resolveGoGitTag(r.repo.Storer, &tags[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	jobs := make(chan int)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := openGoGitStorage(r.gitDir)
+			if err != nil {
+				errs <- err
+				for range jobs {
+				}
+				return
+			}
+			var firstErr error
+			for i := range jobs {
+				if firstErr == nil {
+					firstErr = resolveGoGitTag(s, &tags[i])
+				}
+			}
+			if firstErr != nil {
+				errs <- firstErr
+			}
+		}()
+	}
+	for _, i := range indices {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+func resolveGoGitTag(s storer.EncodedObjectStorer, tag *Tag) error {
+	hash := plumbing.NewHash(tag.Hash)
+	for {
+		obj, err := s.EncodedObject(plumbing.AnyObject, hash)
+		if err == plumbing.ErrObjectNotFound && !tag.Annotated {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if obj.Type() != plumbing.TagObject {
+			tag.Commit = hash.String()
+			return nil
+		}
+		t, err := object.DecodeTag(s, obj)
+		if err != nil {
+			return err
+		}
+		tag.Annotated = true
+		hash = t.Target
+	}
+}
+
+type packedRef struct {
+	Hash   string
+	Peeled string
+}
+
+// readPackedRefs reads the packed-refs file. Peeled values are only trusted
+// (and returned) if git marked the file as containing them.
+func (r *goGitRepository) readPackedRefs() (map[string]packedRef, error) {
+	result := map[string]packedRef{}
+	if r.gitDir == "" {
+		return result, nil
+	}
+	commonDir := r.gitDir
+	if enableCommonDir, err := shouldEnableCommondDir(r.gitDir); err != nil {
+		return nil, err
+	} else if enableCommonDir {
+		if commonDir, err = readCommonDir(r.gitDir); err != nil {
+			return nil, err
+		}
+	}
+	contents, err := os.ReadFile(filepath.Join(commonDir, "packed-refs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return nil, err
+	}
+	peeled := false
+	last := ""
+	for _, line := range strings.Split(string(contents), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# pack-refs with:"):
+			traits := strings.Fields(strings.TrimPrefix(line, "# pack-refs with:"))
+			peeled = containsString(traits, "peeled") || containsString(traits, "fully-peeled")
+		case strings.HasPrefix(line, "^"):
+			if last != "" {
+				ref := result[last]
+				ref.Peeled = strings.TrimSpace(line[1:])
+				result[last] = ref
+			}
+		default:
+			fields := strings.Fields(line)
+			last = ""
+			if len(fields) == 2 && strings.HasPrefix(fields[1], "refs/tags/") {
+				last = fields[1]
+				result[last] = packedRef{Hash: fields[0]}
+			}
+		}
+	}
+	if !peeled {
+		return map[string]packedRef{}, nil
+	}
+	return result, nil
+}
+
+func (r *goGitRepository) TagDate(tag Tag) (time.Time, error) {
+	if tag.Annotated {
+		t, err := r.repo.TagObject(plumbing.NewHash(tag.Hash))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.Tagger.When, nil
+	}
+	c, err := r.repo.CommitObject(plumbing.NewHash(tag.Commit))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Committer.When, nil
+}
+
+func (r *goGitRepository) TagMessage(tag Tag) (string, error) {
+	if !tag.Annotated {
+		return "", nil
+	}
+	t, err := r.repo.TagObject(plumbing.NewHash(tag.Hash))
+	if err != nil {
+		return "", err
+	}
+	return t.Message, nil
+}
+
+func (r *goGitRepository) CreateTag(name string, commit string, message string) error {
+	var opts *git.CreateTagOptions
+	if message != "" {
+		opts = &git.CreateTagOptions{Message: message}
+		// Like git prefer the committer from the environment over the config
+		committerName, committerEmail := os.Getenv("GIT_COMMITTER_NAME"), os.Getenv("GIT_COMMITTER_EMAIL")
+		if committerName != "" && committerEmail != "" {
+			opts.Tagger = &object.Signature{Name: committerName, Email: committerEmail, When: time.Now()}
+		}
+	}
+	_, err := r.repo.CreateTag(name, plumbing.NewHash(commit), opts)
+	return err
+}
+
+// notesCommit returns the commit the notes ref points to (nil if it does not
+// exist yet)
+func (r *goGitRepository) notesCommit(ref string) (*object.Commit, error) {
+	reference, err := r.repo.Reference(plumbing.ReferenceName(ref), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return r.repo.CommitObject(reference.Hash())
+}
+
+// notesEntries returns the blobs of the notes by the hash of the object they
+// annotate, undoing the fan-out of large notes trees (like ab/cdef...)
+func notesEntries(commit *object.Commit) (map[string]plumbing.Hash, error) {
+	result := map[string]plumbing.Hash{}
+	if commit == nil {
+		return result, nil
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		name := strings.ReplaceAll(f.Name, "/", "")
+		if _, err := hex.DecodeString(name); err == nil && (len(name) == 40 || len(name) == 64) {
+			result[name] = f.Hash
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (r *goGitRepository) Notes(ref string) (map[string]string, error) {
+	commit, err := r.notesCommit(ref)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := notesEntries(commit)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]string{}
+	for hash, blobHash := range entries {
+		blob, err := r.repo.BlobObject(blobHash)
+		if err != nil {
+			return nil, err
+		}
+		reader, err := blob.Reader()
+		if err != nil {
+			return nil, err
+		}
+		contents, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		result[hash] = string(contents)
+	}
+	return result, nil
+}
+
+func (r *goGitRepository) SetNote(ref string, hash string, message string) error {
+	signature, err := r.signature()
+	if err != nil {
+		return err
+	}
+	parent, err := r.notesCommit(ref)
+	if err != nil {
+		return err
+	}
+	entries, err := notesEntries(parent)
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	blobHash, err := r.storeObject(plumbing.BlobObject, func(w io.Writer) error {
+		_, err := w.Write([]byte(message))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	entries[hash] = blobHash
+	// Notes trees are written without fan-out, which git reads just as well
+	tree := &object.Tree{}
+	for name, blobHash := range entries {
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: blobHash})
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool { return tree.Entries[i].Name < tree.Entries[j].Name })
+	treeObj := r.repo.Storer.NewEncodedObject()
+	if err := tree.Encode(treeObj); err != nil {
+		return err
+	}
+	treeHash, err := r.repo.Storer.SetEncodedObject(treeObj)
+	if err != nil {
+		return err
+	}
+	commit := &object.Commit{
+		Author:    *signature,
+		Committer: *signature,
+		Message:   "Notes added by 'git notes add'\n",
+		TreeHash:  treeHash,
+	}
+	if parent != nil {
+		commit.ParentHashes = []plumbing.Hash{parent.Hash}
+	}
+	commitObj := r.repo.Storer.NewEncodedObject()
+	if err := commit.Encode(commitObj); err != nil {
+		return err
+	}
+	commitHash, err := r.repo.Storer.SetEncodedObject(commitObj)
+	if err != nil {
+		return err
+	}
+	return r.repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(ref), commitHash))
+}
+
+func (r *goGitRepository) storeObject(t plumbing.ObjectType, write func(w io.Writer) error) (plumbing.Hash, error) {
+	obj := r.repo.Storer.NewEncodedObject()
+	obj.SetType(t)
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := write(w); err != nil {
+		w.Close()
+		return plumbing.ZeroHash, err
+	}
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.repo.Storer.SetEncodedObject(obj)
+}
+
+// signature returns the committer identity like git does, from the
+// environment or else the config
+func (r *goGitRepository) signature() (*object.Signature, error) {
+	name, email := os.Getenv("GIT_COMMITTER_NAME"), os.Getenv("GIT_COMMITTER_EMAIL")
+	if name != "" && email != "" {
+		return &object.Signature{Name: name, Email: email, When: time.Now()}, nil
+	}
+	cfg, err := r.repo.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range [][2]string{{cfg.Committer.Name, cfg.Committer.Email}, {cfg.User.Name, cfg.User.Email}} {
+		if candidate[0] != "" && candidate[1] != "" {
+			return &object.Signature{Name: candidate[0], Email: candidate[1], When: time.Now()}, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to determine the committer identity (set user.name and user.email)")
+}
+
+func (r *goGitRepository) Abbreviate(hash string, minLength int) (string, error) {
+	full := plumbing.NewHash(hash)
+	// Looking up an object makes sure the packfile indexes are loaded
+	if _, err := r.repo.Storer.EncodedObject(plumbing.AnyObject, full); err != nil {
+		return "", err
+	}
+	// Like git rev-parse --short, which abbreviates to at least 4 characters
+	// and at most the full hash
+	if minLength < 4 {
+		minLength = 4
+	} else if minLength > len(hash) {
+		minLength = len(hash)
+	}
+	prefix := full[:minLength/2]
+	candidates := []plumbing.Hash{}
+	if fast, ok := r.repo.Storer.(interface {
+		HashesWithPrefix(prefix []byte) ([]plumbing.Hash, error)
+	}); ok {
+		hashes, err := fast.HashesWithPrefix(prefix)
+		if err != nil {
+			return "", err
+		}
+		candidates = hashes
+	} else {
+		iter, err := r.repo.Storer.IterEncodedObjects(plumbing.AnyObject)
+		if err != nil {
+			return "", err
+		}
+		err = iter.ForEach(func(obj plumbing.EncodedObject) error {
+			if h := obj.Hash(); bytes.HasPrefix(h[:], prefix) {
+				candidates = append(candidates, h)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	length := minLength
+	for _, candidate := range candidates {
+		if candidate == full {
+			continue
+		}
+		other := candidate.String()
+		common := 0
+		for common < len(hash) && common < len(other) && hash[common] == other[common] {
+			common++
+		}
+		if common+1 > length {
+			length = common + 1
+		}
+	}
+	return abbreviateHash(hash, length), nil
+}
+
+func (r *goGitRepository) Shallow() ([]string, error) {
+	shallow, err := r.repo.Storer.Shallow()
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	for _, hash := range shallow {
+		result = append(result, hash.String())
+	}
+	return result, nil
+}
+
+func (r *goGitRepository) Dirty() (bool, error) {
+	worktree, err := r.repo.Worktree()
+	if err == git.ErrIsBareRepository {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return false, err
+	}
+	for _, file := range status {
+		if file.Staging != git.Untracked && (file.Staging != git.Unmodified || file.Worktree != git.Unmodified) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func convertGoGitCommit(c *object.Commit) Commit {
+	parents := []string{}
+	// Only consider parents that are present, i.e. not cut off by a shallow clone
+	c.Parents().ForEach(func(p *object.Commit) error {
+		parents = append(parents, p.Hash.String())
+		return nil
+	})
+	return Commit{
+		Hash:          c.Hash.String(),
+		Parents:       parents,
+		AuthorWhen:    c.Author.When,
+		CommitterWhen: c.Committer.When,
+	}
+}
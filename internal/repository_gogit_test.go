@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoGitRepositoryPackedTags(t *testing.T) {
+	assert := assert.New(t)
+	dir, git := setUpCliRepository(t)
+	git("commit", "--allow-empty", "--quiet", "-m", "first")
+	commit1 := git("rev-parse", "HEAD")
+	git("tag", "v1.0.0")
+	git("tag", "-a", "-m", "Version 1.0.1", "v1.0.1")
+	git("tag", "-a", "-m", "Nested", "nested", "v1.0.1")
+	git("commit", "--allow-empty", "--quiet", "-m", "second")
+	commit2 := git("rev-parse", "HEAD")
+	git("pack-refs", "--all")
+	// A loose ref overrides its stale packed-refs entry
+	git("tag", "-f", "-a", "-m", "Version 1.0.1", "v1.0.1", commit2)
+	git("tag", "-a", "-m", "Version 1.1.0", "v1.1.0")
+
+	packedRefs, err := os.ReadFile(filepath.Join(dir, GitDirName, "packed-refs"))
+	assert.NoError(err)
+	assert.Contains(string(packedRefs), "peeled")
+
+	repo, err := OpenRepository(dir, BackendGoGit)
+	assert.NoError(err)
+	tags, err := repo.Tags(func(name string) bool { return true })
+	assert.NoError(err)
+	commits := map[string]string{}
+	for _, tag := range tags {
+		commits[tag.Name] = tag.Commit
+		assert.Equal(tag.Name != "v1.0.0", tag.Annotated, tag.Name)
+	}
+	assert.Equal(map[string]string{
+		"nested": commit1,
+		"v1.0.0": commit1,
+		"v1.0.1": commit2,
+		"v1.1.0": commit2,
+	}, commits)
+
+	// Without the peeled trait the peeled lines must not be trusted
+	unpeeled := strings.Replace(string(packedRefs), " peeled", "", 1)
+	unpeeled = strings.Replace(unpeeled, " fully-peeled", "", 1)
+	assert.NoError(os.WriteFile(filepath.Join(dir, GitDirName, "packed-refs"), []byte(unpeeled), 0o644))
+	parsed, err := repo.(*goGitRepository).readPackedRefs()
+	assert.NoError(err)
+	assert.Empty(parsed)
+	tags2, err := repo.Tags(func(name string) bool { return true })
+	assert.NoError(err)
+	assert.Equal(tags, tags2)
+}
+
+func setUpBenchmarkRepository(b *testing.B, count int) string {
+	dir, git := setUpCliRepository(b)
+	git("commit", "--allow-empty", "--quiet", "-m", "first")
+	repo, err := gogit.PlainOpen(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		b.Fatal(err)
+	}
+	// Mix annotated, lightweight and non semver tags
+	tagger := &object.Signature{Name: "Test", Email: "test@test.com"}
+	for i := 0; i < count; i++ {
+		switch i % 3 {
+		case 0:
+			_, err = repo.CreateTag(fmt.Sprintf("v%d.0.0", i), head.Hash(), &gogit.CreateTagOptions{Tagger: tagger, Message: "tag"})
+		case 1:
+			_, err = repo.CreateTag(fmt.Sprintf("v%d.0.0", i), head.Hash(), nil)
+		default:
+			_, err = repo.CreateTag(fmt.Sprintf("build-%d", i), head.Hash(), nil)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	git("repack", "-a", "-d", "--quiet")
+	git("pack-refs", "--all")
+	return dir
+}
+
+// naiveGoGitTags resolves every tag sequentially via TagObject as done
+// before packed-refs were taken into account
+func naiveGoGitTags(r *goGitRepository, filter func(name string) bool) ([]Tag, error) {
+	iter, err := r.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	result := []Tag{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if !filter(ref.Name().Short()) {
+			return nil
+		}
+		tag, _ := r.repo.TagObject(ref.Hash())
+		if tag == nil {
+			result = append(result, Tag{Name: ref.Name().Short(), Hash: ref.Hash().String(), Commit: ref.Hash().String()})
+			return nil
+		}
+		c, err := tag.Commit()
+		if err != nil {
+			return err
+		}
+		result = append(result, Tag{Name: ref.Name().Short(), Hash: ref.Hash().String(), Commit: c.Hash.String(), Annotated: true})
+		return nil
+	})
+	return result, err
+}
+
+func semVerTagFilter(name string) bool {
+	return SemVerParse(name) != nil
+}
+
+func benchmarkTags(b *testing.B, tags func(r *goGitRepository) ([]Tag, error)) {
+	dir := setUpBenchmarkRepository(b, 3000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		repo, err := OpenRepository(dir, BackendGoGit)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tags(repo.(*goGitRepository)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGoGitTagsNaive(b *testing.B) {
+	benchmarkTags(b, func(r *goGitRepository) ([]Tag, error) {
+		return naiveGoGitTags(r, semVerTagFilter)
+	})
+}
+
+func BenchmarkGoGitTags(b *testing.B) {
+	benchmarkTags(b, func(r *goGitRepository) ([]Tag, error) {
+		return r.Tags(semVerTagFilter)
+	})
+}
+
+// BenchmarkGoGitTagsConcurrent ignores packed-refs to measure the concurrent
+// peeling alone
+func BenchmarkGoGitTagsConcurrent(b *testing.B) {
+	benchmarkTags(b, func(r *goGitRepository) ([]Tag, error) {
+		result := []Tag{}
+		iter, err := r.repo.Tags()
+		if err != nil {
+			return nil, err
+		}
+		err = iter.ForEach(func(ref *plumbing.Reference) error {
+			if semVerTagFilter(ref.Name().Short()) {
+				result = append(result, Tag{Name: ref.Name().Short(), Hash: ref.Hash().String()})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		indices := make([]int, len(result))
+		for i := range indices {
+			indices[i] = i
+		}
+		return result, r.resolveTags(result, indices)
+	})
+}
@@ -28,6 +28,21 @@ func (v SemVer) Equal(v2 SemVer) bool {
 		equalStringSlice(v.BuildMetadata, v2.BuildMetadata)
 }
 
+// Compare returns -1, 0 or +1 depending on whether v has lower, equal or
+// higher precedence than v2. Prefix and build metadata are ignored.
+func (v SemVer) Compare(v2 SemVer) int {
+	if c := compareInt(v.Major, v2.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, v2.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, v2.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, v2.Prerelease)
+}
+
 // Bump ...
 func (v *SemVer) Bump(nextRelease string) {
 	if nextRelease == "" {
@@ -60,6 +75,22 @@ func (v *SemVer) Bump(nextRelease string) {
 	v.BuildMetadata = []string{}
 }
 
+// Advance increments the given part of the version (defaults to patch) and
+// keeps prerelease and build metadata untouched
+func (v *SemVer) Advance(part string) {
+	switch part {
+	case "major":
+		v.Major++
+		v.Minor = 0
+		v.Patch = 0
+	case "minor":
+		v.Minor++
+		v.Patch = 0
+	default:
+		v.Patch++
+	}
+}
+
 // String ...
 func (v SemVer) String() string {
 	str := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
@@ -98,6 +129,62 @@ func SemVerParse(str string) *SemVer {
 	}
 }
 
+var looseSemVerRegexp = regexp.MustCompile(`^([A-Za-z]+[-_]?)?(\d+)(?:\.(\d+))?(?:\.(\d+))?((?:\.\d+)*)(?:-((?:[0-9A-Za-z-]+)(?:\.[0-9A-Za-z-]+)*))?(?:\+((?:[0-9A-Za-z-]+)(?:\.[0-9A-Za-z-]+)*))?$`)
+
+// SemVerParseLoose is like SemVerParse, but coerces legacy versions like
+// v1.2, release-3 or 1.2.3.4 (whose extra parts become build metadata).
+// Prefixes other than v become v. The applied coercions are returned as
+// human readable descriptions.
+func SemVerParseLoose(str string) (*SemVer, []string) {
+	if v := SemVerParse(str); v != nil {
+		return v, nil
+	}
+	match := looseSemVerRegexp.FindStringSubmatch(str)
+	if len(match) == 0 {
+		return nil, nil
+	}
+
+	coercions := []string{}
+	if match[3] == "" {
+		coercions = append(coercions, "added missing minor version")
+	}
+	if match[4] == "" {
+		coercions = append(coercions, "added missing patch version")
+	}
+	prefix := match[1]
+	if prefix != "" && prefix != "v" {
+		coercions = append(coercions, fmt.Sprintf("replaced prefix %s with v", prefix))
+		prefix = "v"
+	}
+	major, _ := strconv.Atoi(match[2])
+	minor, _ := strconv.Atoi(match[3])
+	patch, _ := strconv.Atoi(match[4])
+	prerelease := stringToSlice(match[6], ".")
+	buildMetadata := stringToSlice(match[7], ".")
+	if extra := strings.TrimPrefix(match[5], "."); extra != "" {
+		buildMetadata = append(stringToSlice(extra, "."), buildMetadata...)
+		coercions = append(coercions, "moved extra version parts to build metadata")
+	}
+
+	return &SemVer{
+		Prefix:        prefix,
+		Major:         major,
+		Minor:         minor,
+		Patch:         patch,
+		Prerelease:    prerelease,
+		BuildMetadata: buildMetadata,
+	}, coercions
+}
+
+// semVerParseTag parses tag names strictly or loosely
+func semVerParseTag(str string, loose bool) *SemVer {
+	if loose {
+		v, _ := SemVerParseLoose(str)
+		return v
+	}
+	return SemVerParse(str)
+}
+
 func stringToSlice(s string, sep string) []string {
 	temp := strings.Split(s, sep)
 	if temp[0] == "" {
@@ -117,3 +204,53 @@ func equalStringSlice(a, b []string) bool {
 	}
 	return true
 }
+
+func compareInt(a, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func comparePrerelease(a, b []string) int {
+	// a version without prerelease has higher precedence
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	if len(a) == 0 {
+		return 1
+	}
+	if len(b) == 0 {
+		return -1
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := comparePrereleaseIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(a), len(b))
+}
+
+func comparePrereleaseIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an < bn {
+			return -1
+		}
+		if an > bn {
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		// numeric identifiers have lower precedence than alphanumeric ones
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
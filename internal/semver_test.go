@@ -59,3 +59,49 @@ func TestSemVerEqual(t *testing.T) {
 	test(SemVer{BuildMetadata: []string{"foo"}}, SemVer{}, false)
 	test(SemVer{}, SemVer{BuildMetadata: []string{"bar"}}, false)
 }
+
+func TestSemVerCompare(t *testing.T) {
+	assert := assert.New(t)
+	test := func(a string, b string, expected int) {
+		actual := SemVerParse(a).Compare(*SemVerParse(b))
+		assert.Equal(expected, actual, "%s <=> %s", a, b)
+	}
+
+	test("1.0.0", "1.0.0", 0)
+	test("v1.0.0", "1.0.0", 0)
+	test("1.0.0+foo", "1.0.0+bar", 0)
+	test("1.0.0", "2.0.0", -1)
+	test("2.0.0", "2.1.0", -1)
+	test("2.1.0", "2.1.1", -1)
+	test("2.1.1", "2.1.0", 1)
+	test("1.0.0-alpha", "1.0.0", -1)
+	test("1.0.0", "1.0.0-alpha", 1)
+	test("1.0.0-alpha", "1.0.0-alpha.1", -1)
+	test("1.0.0-alpha.1", "1.0.0-alpha.beta", -1)
+	test("1.0.0-alpha.beta", "1.0.0-beta", -1)
+	test("1.0.0-beta", "1.0.0-beta.2", -1)
+	test("1.0.0-beta.2", "1.0.0-beta.11", -1)
+	test("1.0.0-beta.11", "1.0.0-rc.1", -1)
+	test("1.0.0-rc.1", "1.0.0", -1)
+}
+
+func TestSemVerParseLoose(t *testing.T) {
+	assert := assert.New(t)
+	test := func(input string, expected *SemVer, expectedCoercions int) {
+		actual, coercions := SemVerParseLoose(input)
+		assert.Equal(expected, actual, input)
+		assert.Len(coercions, expectedCoercions, input)
+	}
+
+	test("v1.2.3", &SemVer{Prefix: "v", Major: 1, Minor: 2, Patch: 3}, 0)
+	test("v1.2", &SemVer{Prefix: "v", Major: 1, Minor: 2}, 1)
+	test("2.0", &SemVer{Major: 2}, 1)
+	test("release-3", &SemVer{Prefix: "v", Major: 3}, 3)
+	test("release_3.1-rc.1", &SemVer{Prefix: "v", Major: 3, Minor: 1, Prerelease: []string{"rc", "1"}}, 2)
+	test("V2.1", &SemVer{Prefix: "v", Major: 2, Minor: 1}, 2)
+	test("1.2.3.4", &SemVer{Major: 1, Minor: 2, Patch: 3, BuildMetadata: []string{"4"}}, 1)
+	test("1.2.3.4.5+foo", &SemVer{Major: 1, Minor: 2, Patch: 3, BuildMetadata: []string{"4", "5", "foo"}}, 1)
+	test("invalid", nil, 0)
+	test("1.", nil, 0)
+	test("release--3", nil, 0)
+}
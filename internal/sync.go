@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ManifestNames are the names of the supported manifests
+var ManifestNames = []string{"VERSION", "package.json", "Chart.yaml", "Cargo.toml", "pyproject.toml", "pom.xml"}
+
+// ManifestChange is a version field of a manifest that differs from the
+// version
+type ManifestChange struct {
+	Path  string
+	Field string
+	From  string
+	To    string
+}
+
+// SyncManifest updates the version fields of the manifest at path to the
+// version in the flavor of its ecosystem, only touching the versions
+// themselves. With check the manifest is left alone. The dev prefix (like
+// dev) tells apart the prerelease of development versions.
+func SyncManifest(path string, version SemVer, devPrefix string, check bool) ([]ManifestChange, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := ManifestFields(path, contents)
+	if err != nil {
+		return nil, err
+	}
+	changes := []ManifestChange{}
+	replacements := map[int]string{}
+	for _, field := range fields {
+		expected := manifestVersion(filepath.Base(path), field, version, devPrefix)
+		if expected == field.Value {
+			continue
+		}
+		changes = append(changes, ManifestChange{Path: path, Field: field.Name, From: field.Value, To: expected})
+		replacements[field.Start] = expected
+	}
+	if check || len(changes) == 0 {
+		return changes, nil
+	}
+	// The fields are in the order of importance, not of their position
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Start < fields[j].Start })
+	updated := []byte{}
+	last := 0
+	for _, field := range fields {
+		if expected, ok := replacements[field.Start]; ok {
+			updated = append(append(updated, contents[last:field.Start]...), expected...)
+			last = field.End
+		}
+	}
+	updated = append(updated, contents[last:]...)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return changes, os.WriteFile(path, updated, info.Mode())
+}
+
+func manifestVersion(name string, field ManifestField, version SemVer, devPrefix string) string {
+	switch {
+	case name == "pyproject.toml":
+		return pep440Version(version, devPrefix)
+	case name == "pom.xml":
+		return mavenVersion(version, devPrefix)
+	case name == "VERSION" || field.Name == "appVersion":
+		// Free-form versions keep their prefix style
+		if !strings.HasPrefix(field.Value, "v") {
+			version.Prefix = ""
+		} else if version.Prefix == "" {
+			version.Prefix = "v"
+		}
+		return version.String()
+	default:
+		version.Prefix = ""
+		return version.String()
+	}
+}
+
+// splitDevPrerelease splits the prerelease into the one of the base version
+// and the part added for the commits since (like dev.5.gabc1234)
+func splitDevPrerelease(prerelease []string, devPrefix string) ([]string, []string) {
+	for i := len(prerelease) - 2; i >= 0; i-- {
+		if prerelease[i] == devPrefix && isNumericIdentifier(prerelease[i+1]) {
+			return prerelease[:i], prerelease[i:]
+		}
+	}
+	return prerelease, nil
+}
+
+func isNumericIdentifier(identifier string) bool {
+	_, err := strconv.ParseUint(identifier, 10, 64)
+	return err == nil
+}
+
+var pep440LabelRegexp = regexp.MustCompile(`^([A-Za-z]+)(\d*)$`)
+
+// pep440Version converts the version for Python packaging (PEP 440), like
+// 1.2.3rc1 for 1.2.3-rc.1 and 1.2.4.dev5+gabc1234 for 1.2.4-dev.5.gabc1234.
+// Identifiers without equivalent become part of the local version.
+func pep440Version(version SemVer, devPrefix string) string {
+	base, dev := splitDevPrerelease(version.Prerelease, devPrefix)
+	result := fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+	local := []string{}
+	if len(base) > 0 {
+		pre := ""
+		if match := pep440LabelRegexp.FindStringSubmatch(base[0]); match != nil {
+			number, rest := match[2], base[1:]
+			if number == "" && len(rest) > 0 && isNumericIdentifier(rest[0]) {
+				number, rest = rest[0], rest[1:]
+			}
+			if number == "" {
+				number = "0"
+			}
+			switch strings.ToLower(match[1]) {
+			case "a", "alpha":
+				pre = "a" + number
+			case "b", "beta":
+				pre = "b" + number
+			case "c", "rc", "pre", "preview":
+				pre = "rc" + number
+			}
+			if pre != "" {
+				local = append(local, rest...)
+			}
+		}
+		if pre == "" {
+			// Unknown prereleases still need to sort before the release
+			local = append(local, base...)
+			if len(dev) == 0 {
+				dev = []string{devPrefix, "0"}
+			}
+		}
+		result += pre
+	}
+	if len(dev) > 0 {
+		result += ".dev" + dev[1]
+		local = append(local, dev[2:]...)
+	}
+	local = append(local, version.BuildMetadata...)
+	if len(local) > 0 {
+		result += "+" + strings.ToLower(strings.ReplaceAll(strings.Join(local, "."), "-", "."))
+	}
+	return result
+}
+
+// mavenVersion converts the version for Maven, where development versions
+// are snapshots and build metadata is not supported
+func mavenVersion(version SemVer, devPrefix string) string {
+	base, dev := splitDevPrerelease(version.Prerelease, devPrefix)
+	result := fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+	if len(base) > 0 {
+		result += "-" + strings.Join(base, ".")
+	}
+	if len(dev) > 0 {
+		result += "-SNAPSHOT"
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPep440Version(t *testing.T) {
+	assert := assert.New(t)
+	test := func(version string, expected string) {
+		assert.Equal(expected, pep440Version(*SemVerParse(version), "dev"), version)
+	}
+
+	test("v1.2.3", "1.2.3")
+	test("v1.2.3-rc.1", "1.2.3rc1")
+	test("v1.2.3-rc1", "1.2.3rc1")
+	test("v1.2.3-alpha", "1.2.3a0")
+	test("v1.2.3-beta.2.x", "1.2.3b2+x")
+	test("v1.2.4-dev.5.gabc1234", "1.2.4.dev5+gabc1234")
+	test("v1.2.4-dev.5", "1.2.4.dev5")
+	test("v1.3.0-rc.1.dev.2.gabc1234", "1.3.0rc1.dev2+gabc1234")
+	test("v1.2.3-foo-bar.1", "1.2.3.dev0+foo.bar.1")
+	test("v1.2.3+build.7", "1.2.3+build.7")
+}
+
+func TestMavenVersion(t *testing.T) {
+	assert := assert.New(t)
+	test := func(version string, expected string) {
+		assert.Equal(expected, mavenVersion(*SemVerParse(version), "dev"), version)
+	}
+
+	test("v1.2.3", "1.2.3")
+	test("v1.2.3-rc.1+build", "1.2.3-rc.1")
+	test("v1.2.4-dev.5.gabc1234", "1.2.4-SNAPSHOT")
+	test("v1.3.0-rc.1.dev.2.gabc1234", "1.3.0-rc.1-SNAPSHOT")
+}
+
+func TestSyncManifest(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	version := *SemVerParse("v1.2.4-dev.5.gabc1234")
+	test := func(name string, contents string, check bool, expected string, expectedChanges []ManifestChange) {
+		path := filepath.Join(dir, name)
+		assert.NoError(os.WriteFile(path, []byte(contents), 0o644))
+		changes, err := SyncManifest(path, version, "dev", check)
+		if !assert.NoError(err, name) {
+			return
+		}
+		for i := range expectedChanges {
+			expectedChanges[i].Path = path
+		}
+		assert.Equal(expectedChanges, changes, name)
+		actual, _ := os.ReadFile(path)
+		assert.Equal(expected, string(actual), name)
+	}
+
+	test("package.json", "{\n  \"name\": \"foo\",\n  \"version\": \"1.0.0\"\n}\n", false,
+		"{\n  \"name\": \"foo\",\n  \"version\": \"1.2.4-dev.5.gabc1234\"\n}\n",
+		[]ManifestChange{{Field: "version", From: "1.0.0", To: "1.2.4-dev.5.gabc1234"}})
+	test("package.json", "{\"version\": \"1.0.0\"}", true, "{\"version\": \"1.0.0\"}",
+		[]ManifestChange{{Field: "version", From: "1.0.0", To: "1.2.4-dev.5.gabc1234"}})
+	test("package.json", "{\"version\": \"1.2.4-dev.5.gabc1234\"}", true, "{\"version\": \"1.2.4-dev.5.gabc1234\"}", []ManifestChange{})
+	test("Chart.yaml", "apiVersion: v2\nversion: 1.0.0 # chart\nappVersion: \"v1.0.0\"\n", false,
+		"apiVersion: v2\nversion: 1.2.4-dev.5.gabc1234 # chart\nappVersion: \"v1.2.4-dev.5.gabc1234\"\n",
+		[]ManifestChange{
+			{Field: "version", From: "1.0.0", To: "1.2.4-dev.5.gabc1234"},
+			{Field: "appVersion", From: "v1.0.0", To: "v1.2.4-dev.5.gabc1234"},
+		})
+	test("Chart.yaml", "appVersion: 1.0.0\nversion: 0.1.0\n", false,
+		"appVersion: 1.2.4-dev.5.gabc1234\nversion: 1.2.4-dev.5.gabc1234\n",
+		[]ManifestChange{
+			{Field: "version", From: "0.1.0", To: "1.2.4-dev.5.gabc1234"},
+			{Field: "appVersion", From: "1.0.0", To: "1.2.4-dev.5.gabc1234"},
+		})
+	test("pyproject.toml", "[project]\nname = 'foo'\nversion = '1.0.0'  # keep\n", false,
+		"[project]\nname = 'foo'\nversion = '1.2.4.dev5+gabc1234'  # keep\n",
+		[]ManifestChange{{Field: "version", From: "1.0.0", To: "1.2.4.dev5+gabc1234"}})
+	test("pom.xml", "<project>\n  <version>1.0.0</version>\n</project>\n", false,
+		"<project>\n  <version>1.2.4-SNAPSHOT</version>\n</project>\n",
+		[]ManifestChange{{Field: "version", From: "1.0.0", To: "1.2.4-SNAPSHOT"}})
+	test("VERSION", "v1.0.0\n", false, "v1.2.4-dev.5.gabc1234\n",
+		[]ManifestChange{{Field: "version", From: "v1.0.0", To: "v1.2.4-dev.5.gabc1234"}})
+}
@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// VerifyTagOptions ...
+type VerifyTagOptions struct {
+	// Allow skipping versions and lower tags that are not ancestors
+	AllowSkip bool
+	// Require the bump level to match the Conventional Commits since the last
+	// release (Version-Bump trailers take precedence)
+	ConventionalCommits bool
+	// Regular expressions matching messages of commits that do not count
+	// towards the bump level
+	ExcludeMessages []string
+	// Coerce legacy tag names like v1.2 into semver
+	Loose bool
+}
+
+// VerifyTag checks that a proposed version tag is valid semver, higher than
+// every ancestor tag and does not skip versions. The tag is expected on HEAD
+// should it not exist yet.
+func VerifyTag(repo Repository, tagName string, opts VerifyTagOptions) error {
+	version := semVerParseTag(tagName, opts.Loose)
+	if version == nil {
+		return fmt.Errorf("tag %s is not a valid semantic version", tagName)
+	}
+	tags, err := repo.Tags(func(name string) bool {
+		return semVerParseTag(name, opts.Loose) != nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to get tags: %v", err)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	commit := ""
+	for _, tag := range tags {
+		if tag.Name == tagName {
+			commit = tag.Commit
+		}
+	}
+	if commit == "" {
+		if commit, err = repo.Head(); err != nil {
+			return fmt.Errorf("unable to find head: %v", err)
+		}
+	}
+	ancestors, err := gitReachable(repo, commit)
+	if err != nil {
+		return err
+	}
+
+	var highest, highestRelease *Tag
+	var highestVersion, highestReleaseVersion *SemVer
+	others := []Tag{}
+	for i, tag := range tags {
+		if tag.Name == tagName {
+			continue
+		}
+		v := semVerParseTag(tag.Name, opts.Loose)
+		c := v.Compare(*version)
+		if c == 0 {
+			return fmt.Errorf("tag %s collides with existing tag %s", tagName, tag.Name)
+		}
+		if !ancestors[tag.Commit] {
+			if c < 0 {
+				others = append(others, tag)
+			}
+			continue
+		}
+		if c > 0 {
+			return fmt.Errorf("tag %s is not higher than ancestor tag %s", tagName, tag.Name)
+		}
+		if highestVersion == nil || v.Compare(*highestVersion) > 0 {
+			highest, highestVersion = &tags[i], v
+		}
+		if len(v.Prerelease) == 0 && (highestReleaseVersion == nil || v.Compare(*highestReleaseVersion) > 0) {
+			highestRelease, highestReleaseVersion = &tags[i], v
+		}
+	}
+
+	if !opts.AllowSkip && highest != nil {
+		if !isNextVersion(*highestVersion, *version) {
+			return fmt.Errorf("tag %s skips versions after ancestor tag %s", tagName, highest.Name)
+		}
+		// Lower tags of the same release line must be ancestors
+		for _, tag := range others {
+			v := semVerParseTag(tag.Name, opts.Loose)
+			if v.Major == version.Major && v.Minor == version.Minor && v.Compare(*highestVersion) > 0 {
+				return fmt.Errorf("tag %s is not a descendant of the lower tag %s", tagName, tag.Name)
+			}
+		}
+	}
+
+	if opts.ConventionalCommits && highestRelease != nil {
+		required, err := gitBumpLevel(repo, "", true, highestRelease.Name, commit, GitDescribeOptions{Loose: opts.Loose, ExcludeMessages: opts.ExcludeMessages})
+		if err != nil {
+			return fmt.Errorf("unable to determine the required release: %v", err)
+		}
+		actual := bumpLevel(*highestReleaseVersion, *version)
+		if required != "" && required != actual {
+			return fmt.Errorf("tag %s is a %s release after %s, but the commits since require a %s release", tagName, actual, highestRelease.Name, required)
+		}
+	}
+	return nil
+}
+
+// isNextVersion checks that v2 is a prerelease or release of the same version
+// as v1 (if that is a prerelease) or of the next major, minor or patch version
+func isNextVersion(v1 SemVer, v2 SemVer) bool {
+	core := func(major, minor, patch int) bool {
+		return v2.Major == major && v2.Minor == minor && v2.Patch == patch
+	}
+	return (len(v1.Prerelease) > 0 && core(v1.Major, v1.Minor, v1.Patch)) ||
+		core(v1.Major, v1.Minor, v1.Patch+1) ||
+		core(v1.Major, v1.Minor+1, 0) ||
+		core(v1.Major+1, 0, 0)
+}
+
+// bumpLevel returns the part incremented from v1 to v2 (major, minor or patch)
+func bumpLevel(v1 SemVer, v2 SemVer) string {
+	switch {
+	case v2.Major != v1.Major:
+		return "major"
+	case v2.Minor != v1.Minor:
+		return "minor"
+	default:
+		return "patch"
+	}
+}
+
+func gitReachable(repo Repository, from string) (map[string]bool, error) {
+	commits, err := repo.Log(from)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get history: %v", err)
+	}
+	result := map[string]bool{}
+	for _, c := range commits {
+		result[c.Hash] = true
+	}
+	return result, nil
+}
+
+// GitHooksDir returns the directory git looks for hooks in
+func GitHooksDir(gitDir string, workTree string) (string, error) {
+	config, err := readGitConfig(gitDir)
+	if err != nil {
+		return "", err
+	}
+	if hooksPath := config["core.hookspath"]; hooksPath != "" {
+		if filepath.IsAbs(hooksPath) {
+			return hooksPath, nil
+		}
+		base := workTree
+		if base == "" {
+			base = gitDir
+		}
+		return filepath.Join(base, hooksPath), nil
+	}
+	commonDir := gitDir
+	if enableCommonDir, err := shouldEnableCommondDir(gitDir); err != nil {
+		return "", err
+	} else if enableCommonDir {
+		if commonDir, err = readCommonDir(gitDir); err != nil {
+			return "", err
+		}
+	}
+	if _, err := os.Stat(commonDir); err != nil {
+		return "", err
+	}
+	return filepath.Join(commonDir, "hooks"), nil
+}
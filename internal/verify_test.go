@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyTag(t *testing.T) {
+	assert := assert.New(t)
+	dir, git := setUpCliRepository(t)
+	git("commit", "--allow-empty", "--quiet", "-m", "first")
+	git("tag", "v1.2.9")
+	commit1 := git("rev-parse", "HEAD")
+	git("commit", "--allow-empty", "--quiet", "-m", "fix: second")
+	git("tag", "v1.2.10-rc.1")
+	git("checkout", "--quiet", "-b", "other", commit1)
+	git("commit", "--allow-empty", "--quiet", "-m", "feat: third")
+	repo, err := OpenRepository(dir, BackendAuto)
+	assert.NoError(err)
+	test := func(tagName string, opts VerifyTagOptions, expectedErr string) {
+		err := VerifyTag(repo, tagName, opts)
+		if expectedErr == "" {
+			assert.NoError(err, tagName)
+		} else {
+			assert.EqualError(err, expectedErr, tagName)
+		}
+	}
+
+	test("v1.3", VerifyTagOptions{}, "tag v1.3 is not a valid semantic version")
+	test("v1.2.8", VerifyTagOptions{}, "tag v1.2.8 is not higher than ancestor tag v1.2.9")
+	test("1.2.9", VerifyTagOptions{}, "tag 1.2.9 collides with existing tag v1.2.9")
+	test("v1.5.0", VerifyTagOptions{}, "tag v1.5.0 skips versions after ancestor tag v1.2.9")
+	test("v1.5.0", VerifyTagOptions{AllowSkip: true}, "")
+	test("v1.2.11", VerifyTagOptions{}, "tag v1.2.11 skips versions after ancestor tag v1.2.9")
+	test("v1.2.10", VerifyTagOptions{}, "tag v1.2.10 is not a descendant of the lower tag v1.2.10-rc.1")
+	test("v1.3.0", VerifyTagOptions{}, "")
+	test("v2.0.0", VerifyTagOptions{}, "")
+	test("v2.0.0", VerifyTagOptions{ConventionalCommits: true}, "tag v2.0.0 is a major release after v1.2.9, but the commits since require a minor release")
+	test("v1.3.0", VerifyTagOptions{ConventionalCommits: true}, "")
+
+	git("checkout", "--quiet", "main")
+	test("v1.2.10", VerifyTagOptions{}, "")
+	test("v1.3.0", VerifyTagOptions{}, "")
+	test("v1.2.10-rc.2", VerifyTagOptions{}, "")
+	test("v1.2.10-beta.1", VerifyTagOptions{}, "tag v1.2.10-beta.1 is not higher than ancestor tag v1.2.10-rc.1")
+	test("v1.3.0", VerifyTagOptions{ConventionalCommits: true}, "tag v1.3.0 is a minor release after v1.2.9, but the commits since require a patch release")
+	test("v1.2.10", VerifyTagOptions{ConventionalCommits: true}, "")
+
+	// Existing tags are verified on their commit
+	git("tag", "v1.2.10-rc.0", commit1)
+	test("v1.2.10-rc.0", VerifyTagOptions{}, "")
+
+	// The required level honours Version-Bump trailers and excluded messages
+	git("checkout", "--quiet", "other")
+	git("commit", "--allow-empty", "--quiet", "-m", "chore: fourth\n\nVersion-Bump: major")
+	test("v1.3.0", VerifyTagOptions{ConventionalCommits: true}, "tag v1.3.0 is a minor release after v1.2.9, but the commits since require a major release")
+	test("v2.0.0", VerifyTagOptions{ConventionalCommits: true}, "")
+	test("v1.3.0", VerifyTagOptions{ConventionalCommits: true, ExcludeMessages: []string{"^chore"}}, "")
+}
+
+func TestGitHooksDir(t *testing.T) {
+	assert := assert.New(t)
+	dir, git := setUpCliRepository(t)
+	gitDir := filepath.Join(dir, GitDirName)
+	hooksDir, err := GitHooksDir(gitDir, dir)
+	assert.NoError(err)
+	assert.Equal(filepath.Join(gitDir, "hooks"), hooksDir)
+
+	git("config", "core.hooksPath", ".githooks")
+	hooksDir, err = GitHooksDir(gitDir, dir)
+	assert.NoError(err)
+	assert.Equal(filepath.Join(dir, ".githooks"), hooksDir)
+
+	_, err = GitHooksDir(filepath.Join(dir, "missing"), "")
+	assert.True(os.IsNotExist(err))
+}
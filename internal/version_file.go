@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// VersionFileData is passed to the templates of version files
+type VersionFileData struct {
+	// The version as generated (like v1.2.4-dev.5.gabc1234)
+	Version       string `json:"version"`
+	Major         int    `json:"major"`
+	Minor         int    `json:"minor"`
+	Patch         int    `json:"patch"`
+	Prerelease    string `json:"prerelease"`
+	BuildMetadata string `json:"buildMetadata"`
+	Commit        string `json:"commit"`
+	Distance      int    `json:"distance"`
+	Dirty         bool   `json:"dirty"`
+	// The version in the flavor of Python (PEP 440)
+	PEP440 string `json:"-"`
+	// The Go package of version.go
+	Package string `json:"-"`
+}
+
+// NewVersionFileData returns the data of the version. The dev prefix (like
+// dev) tells apart the prerelease of development versions.
+func NewVersionFileData(version SemVer, commit string, distance int, dirty bool, devPrefix string) VersionFileData {
+	return VersionFileData{
+		Version:       version.String(),
+		Major:         version.Major,
+		Minor:         version.Minor,
+		Patch:         version.Patch,
+		Prerelease:    strings.Join(version.Prerelease, "."),
+		BuildMetadata: strings.Join(version.BuildMetadata, "."),
+		Commit:        commit,
+		Distance:      distance,
+		Dirty:         dirty,
+		PEP440:        pep440Version(version, devPrefix),
+	}
+}
+
+// VersionFileTemplates are the built-in templates by file extension
+var VersionFileTemplates = map[string]string{
+	".go": `// Code generated by git-describe-semver. DO NOT EDIT.
+
+package {{ .Package }}
+
+const (
+	Version       = {{ quote .Version }}
+	Major         = {{ .Major }}
+	Minor         = {{ .Minor }}
+	Patch         = {{ .Patch }}
+	Prerelease    = {{ quote .Prerelease }}
+	BuildMetadata = {{ quote .BuildMetadata }}
+	Commit        = {{ quote .Commit }}
+	Distance      = {{ .Distance }}
+	Dirty         = {{ .Dirty }}
+)
+`,
+	".h": `/* Generated by git-describe-semver. DO NOT EDIT. */
+
+#ifndef VERSION_H
+#define VERSION_H
+
+#define VERSION {{ quote .Version }}
+#define VERSION_MAJOR {{ .Major }}
+#define VERSION_MINOR {{ .Minor }}
+#define VERSION_PATCH {{ .Patch }}
+#define VERSION_PRERELEASE {{ quote .Prerelease }}
+#define VERSION_BUILD_METADATA {{ quote .BuildMetadata }}
+#define VERSION_COMMIT {{ quote .Commit }}
+#define VERSION_DISTANCE {{ .Distance }}
+#define VERSION_DIRTY {{ if .Dirty }}1{{ else }}0{{ end }}
+
+#endif
+`,
+	".py": `# Generated by git-describe-semver. DO NOT EDIT.
+
+__version__ = version = {{ quote .PEP440 }}
+version_tuple = ({{ .Major }}, {{ .Minor }}, {{ .Patch }})
+semver = {{ quote .Version }}
+prerelease = {{ quote .Prerelease }}
+build_metadata = {{ quote .BuildMetadata }}
+commit = {{ quote .Commit }}
+distance = {{ .Distance }}
+dirty = {{ if .Dirty }}True{{ else }}False{{ end }}
+`,
+	".rs": `// Generated by git-describe-semver. DO NOT EDIT.
+
+pub const VERSION: &str = {{ quote .Version }};
+pub const MAJOR: u64 = {{ .Major }};
+pub const MINOR: u64 = {{ .Minor }};
+pub const PATCH: u64 = {{ .Patch }};
+pub const PRERELEASE: &str = {{ quote .Prerelease }};
+pub const BUILD_METADATA: &str = {{ quote .BuildMetadata }};
+pub const COMMIT: &str = {{ quote .Commit }};
+pub const DISTANCE: u64 = {{ .Distance }};
+pub const DIRTY: bool = {{ .Dirty }};
+`,
+	".json": `{{ json . }}
+`,
+}
+
+var versionFileFuncs = template.FuncMap{
+	"quote": strconv.Quote,
+	"json": func(v interface{}) (string, error) {
+		out, err := json.MarshalIndent(v, "", "  ")
+		return string(out), err
+	},
+}
+
+var goIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RenderVersionFile renders the template (defaults to the built-in template
+// for the file extension of path). The Go package defaults to the name of
+// the directory of path.
+func RenderVersionFile(path string, text string, data VersionFileData) ([]byte, error) {
+	if text == "" {
+		builtin, ok := VersionFileTemplates[filepath.Ext(path)]
+		if !ok {
+			return nil, fmt.Errorf("no built-in template for %s, a template is required", path)
+		}
+		text = builtin
+	}
+	if data.Package == "" {
+		data.Package = "version"
+		if abs, err := filepath.Abs(path); err == nil {
+			if dir := filepath.Base(filepath.Dir(abs)); goIdentifierRegexp.MatchString(dir) {
+				data.Package = dir
+			}
+		}
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(versionFileFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse template: %v", err)
+	}
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, data); err != nil {
+		return nil, fmt.Errorf("unable to render %s: %v", path, err)
+	}
+	return out.Bytes(), nil
+}
+
+// WriteVersionFile writes the contents to path unless it already has them,
+// so that incremental builds are not triggered needlessly. Returns whether
+// the file was written.
+func WriteVersionFile(path string, contents []byte) (bool, error) {
+	existing, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(existing, contents) {
+		return false, nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, contents, 0o644)
+}
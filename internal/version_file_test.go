@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderVersionFile(t *testing.T) {
+	assert := assert.New(t)
+	data := NewVersionFileData(*SemVerParse("v1.2.4-dev.5.gabc1234+build"), "abc1234def", 5, true, "dev")
+	assert.Equal("1.2.4.dev5+gabc1234.build", data.PEP440)
+
+	out, err := RenderVersionFile("pkg/buildinfo/version.go", "", data)
+	assert.NoError(err)
+	_, err = parser.ParseFile(token.NewFileSet(), "version.go", out, 0)
+	assert.NoError(err)
+	assert.Contains(string(out), "package buildinfo\n")
+	assert.Contains(string(out), "\tVersion       = \"v1.2.4-dev.5.gabc1234+build\"\n")
+	assert.Contains(string(out), "\tDirty         = true\n")
+	data.Package = "main"
+	out, _ = RenderVersionFile("version.go", "", data)
+	assert.Contains(string(out), "package main\n")
+
+	out, err = RenderVersionFile("include/version.h", "", data)
+	assert.NoError(err)
+	assert.Contains(string(out), "#define VERSION_MAJOR 1\n")
+	assert.Contains(string(out), "#define VERSION_DIRTY 1\n")
+
+	out, err = RenderVersionFile("_version.py", "", data)
+	assert.NoError(err)
+	assert.Contains(string(out), "__version__ = version = \"1.2.4.dev5+gabc1234.build\"\n")
+	assert.Contains(string(out), "dirty = True\n")
+
+	out, err = RenderVersionFile("src/version.rs", "", data)
+	assert.NoError(err)
+	assert.Contains(string(out), "pub const DISTANCE: u64 = 5;\n")
+
+	out, err = RenderVersionFile("version.json", "", data)
+	assert.NoError(err)
+	decoded := map[string]interface{}{}
+	assert.NoError(json.Unmarshal(out, &decoded))
+	assert.Equal(map[string]interface{}{
+		"version":       "v1.2.4-dev.5.gabc1234+build",
+		"major":         1.0,
+		"minor":         2.0,
+		"patch":         4.0,
+		"prerelease":    "dev.5.gabc1234",
+		"buildMetadata": "build",
+		"commit":        "abc1234def",
+		"distance":      5.0,
+		"dirty":         true,
+	}, decoded)
+
+	out, err = RenderVersionFile("VERSION.txt", "{{ .Major }}.{{ .Minor }} ({{ .Commit }})\n", data)
+	assert.NoError(err)
+	assert.Equal("1.2 (abc1234def)\n", string(out))
+
+	_, err = RenderVersionFile("VERSION.txt", "", data)
+	assert.EqualError(err, "no built-in template for VERSION.txt, a template is required")
+	_, err = RenderVersionFile("VERSION.txt", "{{ .Unknown }}", data)
+	assert.Error(err)
+}
+
+func TestWriteVersionFile(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "sub", "version.json")
+
+	written, err := WriteVersionFile(path, []byte("first"))
+	assert.NoError(err)
+	assert.True(written)
+	info, _ := os.Stat(path)
+	modTime := info.ModTime()
+
+	written, err = WriteVersionFile(path, []byte("first"))
+	assert.NoError(err)
+	assert.False(written)
+	info, _ = os.Stat(path)
+	assert.Equal(modTime, info.ModTime())
+
+	written, err = WriteVersionFile(path, []byte("second"))
+	assert.NoError(err)
+	assert.True(written)
+	contents, _ := os.ReadFile(path)
+	assert.Equal("second", string(contents))
+}
@@ -0,0 +1,430 @@
+// Package describe generates semantic versions from the git history like
+// the git-describe-semver command line tool does.
+package describe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+	"github.com/choffmeister/git-describe-semver/pkg/semver"
+)
+
+const (
+	BackendAuto  = internal.BackendAuto
+	BackendGoGit = internal.BackendGoGit
+	BackendGit   = internal.BackendGit
+)
+
+const (
+	BaseNearest = internal.BaseNearest
+	BaseHighest = internal.BaseHighest
+)
+
+const (
+	TimestampSourceNow             = internal.TimestampSourceNow
+	TimestampSourceCommit          = internal.TimestampSourceCommit
+	TimestampSourceAuthor          = internal.TimestampSourceAuthor
+	TimestampSourceTag             = internal.TimestampSourceTag
+	TimestampSourceSourceDateEpoch = internal.TimestampSourceSourceDateEpoch
+)
+
+const (
+	TimestampLayoutUnix        = internal.TimestampLayoutUnix
+	TimestampLayoutDateTime    = internal.TimestampLayoutDateTime
+	TimestampLayoutDateCounter = internal.TimestampLayoutDateCounter
+)
+
+const (
+	CollisionIgnore  = internal.CollisionIgnore
+	CollisionWarn    = internal.CollisionWarn
+	CollisionFail    = internal.CollisionFail
+	CollisionAdvance = internal.CollisionAdvance
+)
+
+// IncompleteHistoryError is returned when the repository is a shallow clone
+// or has no tags at all and this has not been explicitly allowed
+type IncompleteHistoryError = internal.IncompleteHistoryError
+
+// Options mirror the flags of the command line tool. The zero value
+// describes the git worktree in the current directory.
+type Options struct {
+	// Directory inside the git worktree (defaults to the current directory)
+	Dir string
+	// How to access the git repository (defaults to BackendAuto)
+	Backend string
+	// Do not use or update the describe cache in the git directory
+	NoCache bool
+	// The first version to fallback to should there be no tag
+	Fallback string
+	// Manifest to read the fallback from instead (VERSION, package.json,
+	// Chart.yaml, Cargo.toml, pyproject.toml or pom.xml), relative to Dir
+	FallbackFrom string
+	// Also use the fallback if it is higher than the tag (unless the tag is
+	// on HEAD)
+	FallbackIfHigher bool
+	// Which tag to use as base version (defaults to BaseNearest)
+	Base string
+	// Fall back instead of failing when the repository is a shallow clone
+	AllowShallow bool
+	// Fall back instead of failing when the repository has no tags at all
+	AllowNoTags bool
+	// Ignore tags created after this point in time (date for its end in UTC,
+	// @unix-timestamp or commit-time)
+	AsOf string
+	// Drop prefix from the version
+	DropPrefix bool
+	// Suffix to add to prereleases
+	PrereleaseSuffix string
+	// Prefix to use as start of prerelease (defaults to "dev")
+	PrereleasePrefix string
+	// Use timestamp instead of commit count for prerelease
+	PrereleaseTimestamped bool
+	// Source of the timestamp for timestamped prereleases (defaults to
+	// TimestampSourceNow)
+	TimestampSource string
+	// Layout of the timestamp for timestamped prereleases (defaults to
+	// TimestampLayoutUnix)
+	TimestampLayout string
+	// Bump current version to next release (major, minor or patch).
+	// Version-Bump trailers of the commits since the base tag can raise it.
+	NextRelease string
+	// Compute the next prerelease tag of this channel (like alpha, beta or
+	// rc) for the next release
+	NextPrerelease string
+	// Format of Result.Output (use <version> as placeholder)
+	Format string
+	// Minimum number of hash characters, 0 omits the hash. Defaults to the
+	// shortest unique prefix with at least 7 characters.
+	Abbrev *int
+	// What to do should the version collide with an existing tag (defaults
+	// to CollisionIgnore)
+	OnCollision string
+	// Coerce legacy tags like v1.2, release-3 or 1.2.3.4 into semver instead
+	// of ignoring them
+	Loose bool
+	// Regular expressions matching messages of commits (like release
+	// commits) that neither count towards the distance nor the next release
+	ExcludeMessages []string
+	// Notes ref with version notes, which override the version on HEAD and
+	// serve as base version on ancestors (defaults to refs/notes/versions)
+	NotesRef string
+	// Ignore version notes
+	NoNotes bool
+	// Determine whether the worktree has uncommitted changes (see
+	// Result.Dirty), which requires scanning it
+	DetectDirty bool
+	// Called with warnings, like collisions with CollisionWarn
+	Warn func(msg string)
+	// Called with verbose information, like the coercions applied with Loose
+	Info func(msg string)
+}
+
+// Result ...
+type Result struct {
+	// The generated version
+	Version semver.Version
+	// The generated version formatted according to Options.Format
+	Output string
+	// Name of the tag the version is based on (empty if there is none)
+	Tag string
+	// Number of commits since the tag
+	Distance int
+	// Hash of the described commit
+	Commit string
+	// Committer time of the described commit
+	CommitTime time.Time
+	// Whether tracked files of the worktree have uncommitted changes (only
+	// determined with Options.DetectDirty)
+	Dirty bool
+}
+
+// Describe generates the version for the HEAD of the git repository
+func Describe(ctx context.Context, opts Options) (Result, error) {
+	if opts.PrereleasePrefix == "" {
+		opts.PrereleasePrefix = "dev"
+	}
+	if opts.NotesRef == "" {
+		opts.NotesRef = internal.DefaultNotesRef
+	}
+	if opts.NoNotes {
+		opts.NotesRef = ""
+	}
+	describeOpts := internal.GitDescribeOptions{
+		Base:            opts.Base,
+		AllowShallow:    opts.AllowShallow,
+		AllowNoTags:     opts.AllowNoTags,
+		Loose:           opts.Loose,
+		ExcludeMessages: opts.ExcludeMessages,
+		NotesRef:        opts.NotesRef,
+	}
+	generateOpts := internal.GenerateVersionOptions{
+		FallbackTagName:       opts.Fallback,
+		DropTagNamePrefix:     opts.DropPrefix,
+		PrereleaseSuffix:      opts.PrereleaseSuffix,
+		PrereleasePrefix:      opts.PrereleasePrefix,
+		PrereleaseTimestamped: opts.PrereleaseTimestamped,
+		TimestampLayout:       opts.TimestampLayout,
+		NextRelease:           opts.NextRelease,
+		Format:                opts.Format,
+		Collision:             opts.OnCollision,
+		Warn:                  opts.Warn,
+		Loose:                 opts.Loose,
+		NextPrerelease:        opts.NextPrerelease,
+		FallbackIfHigher:      opts.FallbackIfHigher,
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+	if opts.FallbackFrom != "" {
+		path := opts.FallbackFrom
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		fallback, err := internal.ReadManifestVersion(path)
+		if err != nil {
+			return Result{}, fmt.Errorf("unable to read fallback: %w", err)
+		}
+		valid := internal.SemVerParse(fallback) != nil
+		if opts.Loose {
+			coerced, _ := internal.SemVerParseLoose(fallback)
+			valid = coerced != nil
+		}
+		if !valid {
+			return Result{}, fmt.Errorf("fallback %s from %s is not a semantic version", fallback, opts.FallbackFrom)
+		}
+		generateOpts.FallbackTagName = fallback
+	}
+	repo, err := internal.OpenRepository(dir, opts.Backend)
+	if errors.Is(err, internal.ErrNotRepository) {
+		// Source archives created by git archive have no repository
+		if path, archivalErr := internal.FindArchival(dir); archivalErr == nil {
+			return describeArchival(ctx, path, opts, describeOpts, generateOpts)
+		}
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to open git repository: %w", err)
+	}
+	if opts.AsOf != "" {
+		asOf, err := internal.GitParseAsOf(repo, opts.AsOf)
+		if err != nil {
+			return Result{}, fmt.Errorf("unable to parse as-of: %w", err)
+		}
+		describeOpts.AsOf = *asOf
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	describe := internal.GitDescribeCached
+	tags := internal.GitTagsCached
+	if opts.NoCache {
+		describe = internal.GitDescribe
+		tags = internal.GitTags
+	}
+	tagName, counter, headHash, err := describe(repo, describeOpts)
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to describe commit: %w", err)
+	}
+	if opts.NextRelease != "" {
+		nextRelease, err := internal.GitNextRelease(repo, opts.NextRelease, *tagName, *headHash, describeOpts)
+		if err != nil {
+			return Result{}, fmt.Errorf("unable to determine next release: %w", err)
+		}
+		if opts.Info != nil && nextRelease != opts.NextRelease {
+			opts.Info(fmt.Sprintf("raised next release %s to %s by Version-Bump trailers since the base tag", opts.NextRelease, nextRelease))
+		}
+		generateOpts.NextRelease = nextRelease
+	}
+	if opts.Loose && opts.Info != nil {
+		if err := reportCoercions(repo, describeOpts, tags, opts.Info); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	timestamp := describeOpts.AsOf
+	explicitSource := opts.TimestampSource != "" && opts.TimestampSource != internal.TimestampSourceNow
+	if generateOpts.PrereleaseTimestamped && (timestamp.IsZero() || explicitSource) {
+		t, err := internal.GitTimestamp(repo, opts.TimestampSource, *tagName)
+		if err != nil {
+			return Result{}, fmt.Errorf("unable to determine timestamp: %w", err)
+		}
+		timestamp = *t
+	}
+	if opts.Abbrev != nil && *opts.Abbrev == 0 {
+		generateOpts.Abbrev = opts.Abbrev
+	} else if generateOpts.NextRelease == "" && generateOpts.NextPrerelease == "" && (*counter > 0 || *tagName == "") {
+		// Only look for a unique abbreviation when the hash is part of the version
+		minLength := 7
+		if opts.Abbrev != nil {
+			minLength = *opts.Abbrev
+		}
+		abbreviated, err := repo.Abbreviate(*headHash, minLength)
+		if err != nil {
+			return Result{}, fmt.Errorf("unable to abbreviate hash: %w", err)
+		}
+		length := len(abbreviated)
+		generateOpts.Abbrev = &length
+	}
+	checkCollisions := generateOpts.Collision != "" && generateOpts.Collision != internal.CollisionIgnore
+	if checkCollisions || generateOpts.NextPrerelease != "" {
+		existingTags, err := tags(repo, describeOpts)
+		if err != nil {
+			return Result{}, fmt.Errorf("unable to get tags: %w", err)
+		}
+		generateOpts.ExistingTags = *existingTags
+	}
+	commit, err := repo.Commit(*headHash)
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to get commit: %w", err)
+	}
+	dirty := false
+	if opts.DetectDirty {
+		dirty, err = repo.Dirty()
+		if err != nil {
+			return Result{}, fmt.Errorf("unable to determine worktree status: %w", err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	result, err := generate(*tagName, *counter, *headHash, timestamp, opts, generateOpts)
+	if err != nil {
+		return Result{}, err
+	}
+	result.CommitTime = commit.CommitterWhen
+	result.Dirty = dirty
+	return result, nil
+}
+
+func generate(tagName string, counter int, headHash string, timestamp time.Time, opts Options, generateOpts internal.GenerateVersionOptions) (Result, error) {
+	version, err := internal.GenerateSemVer(tagName, counter, headHash, timestamp, generateOpts)
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to generate version: %w", err)
+	}
+	result := Result{
+		Version:  fromInternal(*version),
+		Output:   internal.FormatVersion(*version, opts.Format),
+		Tag:      tagName,
+		Distance: counter,
+		Commit:   headHash,
+	}
+	return result, nil
+}
+
+// InternalVersion converts the version for the internal packages of this
+// module (like the one of a result for syncing manifests)
+func InternalVersion(v semver.Version) internal.SemVer {
+	return internal.SemVer{
+		Prefix:        v.Prefix,
+		Major:         v.Major,
+		Minor:         v.Minor,
+		Patch:         v.Patch,
+		Prerelease:    v.Prerelease,
+		BuildMetadata: v.BuildMetadata,
+	}
+}
+
+// fromInternal converts the version like semver.Parse does, without
+// requiring it to be valid
+func fromInternal(v internal.SemVer) semver.Version {
+	if len(v.Prerelease) == 0 {
+		v.Prerelease = nil
+	}
+	if len(v.BuildMetadata) == 0 {
+		v.BuildMetadata = nil
+	}
+	return semver.Version{
+		Prefix:        v.Prefix,
+		Major:         v.Major,
+		Minor:         v.Minor,
+		Patch:         v.Patch,
+		Prerelease:    v.Prerelease,
+		BuildMetadata: v.BuildMetadata,
+	}
+}
+
+// describeArchival generates the version from the archival file of a source
+// archive. Everything that needs the history or all tags is unavailable.
+func describeArchival(ctx context.Context, path string, opts Options, describeOpts internal.GitDescribeOptions, generateOpts internal.GenerateVersionOptions) (Result, error) {
+	if opts.NextPrerelease != "" {
+		return Result{}, fmt.Errorf("unable to determine the next release of a source archive without git repository")
+	}
+	if opts.Base == BaseHighest {
+		return Result{}, fmt.Errorf("unable to determine the highest tag of a source archive without git repository")
+	}
+	if len(opts.ExcludeMessages) > 0 {
+		return Result{}, fmt.Errorf("unable to exclude commits of a source archive without git repository")
+	}
+	if opts.NotesRef != "" && opts.NotesRef != internal.DefaultNotesRef {
+		return Result{}, fmt.Errorf("unable to read notes of a source archive without git repository")
+	}
+	archival, err := internal.ReadArchival(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to read archival file: %w", err)
+	}
+	tagName, counter, headHash, err := archival.Describe(describeOpts)
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to describe archive: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	timestamp := time.Time{}
+	if generateOpts.PrereleaseTimestamped {
+		switch opts.TimestampSource {
+		case "", internal.TimestampSourceNow:
+			timestamp = time.Now()
+		case internal.TimestampSourceSourceDateEpoch:
+			t, err := internal.GitTimestamp(nil, opts.TimestampSource, tagName)
+			if err != nil {
+				return Result{}, fmt.Errorf("unable to determine timestamp: %w", err)
+			}
+			timestamp = *t
+		default:
+			// The archive only knows the committer time of its commit
+			timestamp = archival.NodeDate
+		}
+	}
+	// Take the abbreviation git chose as of creating the archive, as other
+	// objects are unknown
+	length := archival.AbbrevLength()
+	if opts.Abbrev != nil && (*opts.Abbrev == 0 || *opts.Abbrev > length) {
+		length = *opts.Abbrev
+	}
+	generateOpts.Abbrev = &length
+	// Other tags are unknown, so collisions cannot be detected
+	generateOpts.Collision = internal.CollisionIgnore
+	result, err := generate(tagName, counter, headHash, timestamp, opts, generateOpts)
+	if err != nil {
+		return Result{}, err
+	}
+	result.CommitTime = archival.NodeDate
+	return result, nil
+}
+
+func reportCoercions(repo internal.Repository, opts internal.GitDescribeOptions, tags func(internal.Repository, internal.GitDescribeOptions) (*map[string]string, error), info func(msg string)) error {
+	tagMap, err := tags(repo, opts)
+	if err != nil {
+		return fmt.Errorf("unable to get tags: %w", err)
+	}
+	tagNames := []string{}
+	for tagName := range *tagMap {
+		tagNames = append(tagNames, tagName)
+	}
+	sort.Strings(tagNames)
+	for _, tagName := range tagNames {
+		if version, coercions := internal.SemVerParseLoose(tagName); len(coercions) > 0 {
+			info(fmt.Sprintf("coerced tag %s to %s (%s)", tagName, version, strings.Join(coercions, ", ")))
+		}
+	}
+	return nil
+}
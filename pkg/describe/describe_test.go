@@ -0,0 +1,234 @@
+package describe
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+	"github.com/choffmeister/git-describe-semver/pkg/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	defer os.RemoveAll(dir)
+	author := object.Signature{Name: "Test", Email: "test@test.com"}
+	ctx := context.Background()
+	_, err := Describe(ctx, Options{Dir: dir})
+	assert.Error(err)
+
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	_, err = Describe(ctx, Options{Dir: dir})
+	assert.Error(err)
+
+	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
+	_, err = Describe(ctx, Options{Dir: dir})
+	assert.True(errors.As(err, &IncompleteHistoryError{}))
+	repo.CreateTag("invalid", commit1, nil)
+	_, err = Describe(ctx, Options{Dir: dir})
+	assert.Error(err)
+
+	commit2, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
+	repo.CreateTag("v1.0.0", commit2, nil)
+
+	commit3, _ := worktree.Commit("second", &git.CommitOptions{Author: &author})
+	result, err := Describe(ctx, Options{Dir: dir, Format: "version=<version>"})
+	assert.NoError(err)
+	commit3Object, _ := repo.CommitObject(commit3)
+	assert.True(commit3Object.Committer.When.Equal(result.CommitTime))
+	assert.Equal(Result{
+		Version:    semver.MustParse("v1.0.1-dev.1.g" + commit3.String()[0:7]),
+		Output:     "version=v1.0.1-dev.1.g" + commit3.String()[0:7],
+		Tag:        "v1.0.0",
+		Distance:   1,
+		Commit:     commit3.String(),
+		CommitTime: result.CommitTime,
+	}, result)
+	for _, abbrev := range []int{0, 12} {
+		result, err := Describe(ctx, Options{Dir: dir, Abbrev: &abbrev})
+		assert.NoError(err)
+		expected := "v1.0.1-dev.1"
+		if abbrev > 0 {
+			expected += ".g" + commit3.String()[0:abbrev]
+		}
+		assert.Equal(expected, result.Output)
+	}
+	// Suffixes like branch names are not necessarily valid semantic versions
+	result, err = Describe(ctx, Options{Dir: dir, PrereleaseSuffix: "feature/foo"})
+	assert.NoError(err)
+	assert.Equal("v1.0.1-dev.1.g"+commit3.String()[0:7]+"-feature/foo", result.Output)
+	assert.Equal([]string{"dev", "1", "g" + commit3.String()[0:7] + "-feature/foo"}, result.Version.Prerelease)
+
+	worktree.Checkout(&git.CheckoutOptions{Hash: commit2})
+	commit4, _ := worktree.Commit("hotfix", &git.CommitOptions{Author: &author})
+	repo.CreateTag("v1.0.1", commit4, nil)
+	worktree.Checkout(&git.CheckoutOptions{Hash: commit3})
+	_, err = Describe(ctx, Options{Dir: dir, NextRelease: "patch", OnCollision: CollisionFail})
+	assert.Error(err)
+	result, err = Describe(ctx, Options{Dir: dir, NextRelease: "patch", OnCollision: CollisionAdvance})
+	assert.NoError(err)
+	assert.Equal("v1.0.2", result.Output)
+
+	worktree.Commit("feat: third\n\nVersion-Bump: minor", &git.CommitOptions{Author: &author})
+	worktree.Commit("chore(release): 1.1.0", &git.CommitOptions{Author: &author})
+	result, err = Describe(ctx, Options{Dir: dir, NextRelease: "patch"})
+	assert.NoError(err)
+	assert.Equal("v1.1.0", result.Output)
+	result, err = Describe(ctx, Options{Dir: dir, ExcludeMessages: []string{`^chore\(release\)`}})
+	assert.NoError(err)
+	assert.Equal(2, result.Distance)
+	assert.False(result.Dirty)
+
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("file"), 0o644)
+	worktree.Add("file.txt")
+	result, err = Describe(ctx, Options{Dir: dir, DetectDirty: true})
+	assert.NoError(err)
+	assert.True(result.Dirty)
+	result, err = Describe(ctx, Options{Dir: dir})
+	assert.NoError(err)
+	assert.False(result.Dirty)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = Describe(canceled, Options{Dir: dir})
+	assert.ErrorIs(err, context.Canceled)
+}
+
+func TestDescribeLoose(t *testing.T) {
+	assert := assert.New(t)
+	dir, _ := ioutil.TempDir("", "example")
+	defer os.RemoveAll(dir)
+	author := object.Signature{Name: "Test", Email: "test@test.com"}
+	ctx := context.Background()
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+
+	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
+	repo.CreateTag("v1.2", commit1, nil)
+	commit2, _ := worktree.Commit("second", &git.CommitOptions{Author: &author})
+	_, err := Describe(ctx, Options{Dir: dir, NoCache: true})
+	assert.Error(err)
+
+	infos := []string{}
+	result, err := Describe(ctx, Options{Dir: dir, NoCache: true, Loose: true, Info: func(msg string) { infos = append(infos, msg) }})
+	assert.NoError(err)
+	assert.Equal("v1.2.1-dev.1.g"+commit2.String()[0:7], result.Output)
+	assert.Equal([]string{"coerced tag v1.2 to v1.2.0 (added missing patch version)"}, infos)
+
+	repo.CreateTag("release-3", commit2, nil)
+	result, err = Describe(ctx, Options{Dir: dir, NoCache: true, Loose: true})
+	assert.NoError(err)
+	assert.Equal("v3.0.0", result.Output)
+	assert.Equal(semver.Version{Prefix: "v", Major: 3}, result.Version)
+	result, err = Describe(ctx, Options{Dir: dir, NoCache: true, Loose: true, DropPrefix: true})
+	assert.NoError(err)
+	assert.Equal("3.0.0", result.Output)
+}
+
+func TestDescribeArchive(t *testing.T) {
+	assert := assert.New(t)
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "test")
+	}
+	t.Setenv("GIT_CEILING_DIRECTORIES", os.TempDir())
+	ctx := context.Background()
+	dir := t.TempDir()
+	run := func(dir string, args ...string) {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		assert.NoError(err, string(out))
+	}
+	run(dir, "git", "init", "--quiet")
+	assert.NoError(internal.WriteArchivalFiles(dir, false))
+	run(dir, "git", "add", "--all")
+	run(dir, "git", "commit", "--quiet", "-m", "first")
+	run(dir, "git", "tag", "v1.0.0")
+	run(dir, "git", "commit", "--quiet", "--allow-empty", "-m", "second")
+	expected, err := Describe(ctx, Options{Dir: dir})
+	assert.NoError(err)
+
+	archiveDir := t.TempDir()
+	run(dir, "git", "archive", "--output", filepath.Join(archiveDir, "archive.tar"), "HEAD")
+	run(archiveDir, "tar", "xf", "archive.tar")
+	actual, err := Describe(ctx, Options{Dir: archiveDir})
+	assert.NoError(err)
+	assert.True(expected.CommitTime.Equal(actual.CommitTime))
+	actual.CommitTime = expected.CommitTime
+	assert.Equal(expected, actual)
+	assert.Equal("v1.0.1-dev.1.g"+actual.Commit[0:7], actual.Output)
+	for _, opts := range []Options{
+		{Dir: archiveDir, NextPrerelease: "rc"},
+		{Dir: archiveDir, Base: BaseHighest},
+		{Dir: archiveDir, ExcludeMessages: []string{"^chore"}},
+		{Dir: archiveDir, NotesRef: "refs/notes/other"},
+	} {
+		_, err = Describe(ctx, opts)
+		assert.Error(err)
+	}
+	for _, abbrev := range []int{0, 3, 12} {
+		result, err := Describe(ctx, Options{Dir: archiveDir, Abbrev: &abbrev})
+		assert.NoError(err)
+		expected := "v1.0.1-dev.1"
+		if abbrev > 0 {
+			expected += ".g" + actual.Commit[0:max(abbrev, 7)]
+		}
+		assert.Equal(expected, result.Output)
+	}
+
+	// The hash is as long as git abbreviated it when creating the archive
+	run(dir, "git", "config", "core.abbrev", "10")
+	archiveDir = t.TempDir()
+	run(dir, "git", "archive", "--output", filepath.Join(archiveDir, "archive.tar"), "HEAD")
+	run(archiveDir, "tar", "xf", "archive.tar")
+	actual, err = Describe(ctx, Options{Dir: archiveDir})
+	assert.NoError(err)
+	assert.Equal("v1.0.1-dev.1.g"+actual.Commit[0:10], actual.Output)
+
+	// Without archival file the missing repository is reported
+	assert.NoError(os.Remove(filepath.Join(archiveDir, internal.ArchivalFileName)))
+	_, err = Describe(ctx, Options{Dir: archiveDir})
+	assert.ErrorIs(err, internal.ErrNotRepository)
+}
+
+func TestDescribeFallbackFrom(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	author := object.Signature{Name: "Test", Email: "test@test.com"}
+	ctx := context.Background()
+	repo, _ := git.PlainInit(dir, false)
+	worktree, _ := repo.Worktree()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"version": "3.0.0"}`), 0o644))
+
+	_, err := Describe(ctx, Options{Dir: dir, AllowNoTags: true, FallbackFrom: "Cargo.toml"})
+	assert.Error(err)
+	commit1, _ := worktree.Commit("first", &git.CommitOptions{Author: &author})
+	result, err := Describe(ctx, Options{Dir: dir, AllowNoTags: true, FallbackFrom: "package.json"})
+	assert.NoError(err)
+	assert.Equal("3.0.0-dev.1.g"+commit1.String()[0:7], result.Output)
+
+	repo.CreateTag("v2.1.0", commit1, nil)
+	commit2, _ := worktree.Commit("second", &git.CommitOptions{Author: &author})
+	result, err = Describe(ctx, Options{Dir: dir, FallbackFrom: "package.json"})
+	assert.NoError(err)
+	assert.Equal("v2.1.1-dev.1.g"+commit2.String()[0:7], result.Output)
+	result, err = Describe(ctx, Options{Dir: dir, FallbackFrom: "package.json", FallbackIfHigher: true})
+	assert.NoError(err)
+	assert.Equal("v3.0.0-dev.1.g"+commit2.String()[0:7], result.Output)
+
+	assert.NoError(os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"version": "3.0"}`), 0o644))
+	_, err = Describe(ctx, Options{Dir: dir, FallbackFrom: "package.json"})
+	assert.EqualError(err, "fallback 3.0 from package.json is not a semantic version")
+	result, err = Describe(ctx, Options{Dir: dir, FallbackFrom: "package.json", FallbackIfHigher: true, Loose: true})
+	assert.NoError(err)
+	assert.Equal("v3.0.0-dev.1.g"+commit2.String()[0:7], result.Output)
+}
@@ -0,0 +1,167 @@
+// Package semver provides a semantic version type that tolerates an
+// alphabetic prefix like "v" as commonly used in git tags.
+package semver
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/choffmeister/git-describe-semver/internal"
+)
+
+// Version is a semantic version with an optional prefix. The zero value
+// represents 0.0.0.
+type Version struct {
+	Prefix        string
+	Major         int
+	Minor         int
+	Patch         int
+	Prerelease    []string
+	BuildMetadata []string
+}
+
+// Parse parses a semantic version with an optional prefix (like v1.2.3)
+func Parse(str string) (Version, error) {
+	v := internal.SemVerParse(str)
+	if v == nil {
+		return Version{}, fmt.Errorf("invalid semantic version %q", str)
+	}
+	return fromInternal(*v), nil
+}
+
+// ParseLoose is like Parse, but coerces legacy versions like v1.2, release-3
+// or 1.2.3.4 (whose extra parts become build metadata). Prefixes other than
+// v become v. The applied coercions are returned as human readable
+// descriptions.
+func ParseLoose(str string) (Version, []string, error) {
+	v, coercions := internal.SemVerParseLoose(str)
+	if v == nil {
+		return Version{}, nil, fmt.Errorf("invalid version %q", str)
+	}
+	return fromInternal(*v), coercions, nil
+}
+
+// MustParse is like Parse, but panics if the version cannot be parsed
+func MustParse(str string) Version {
+	v, err := Parse(str)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// String implements fmt.Stringer and flag.Value
+func (v Version) String() string {
+	return v.toInternal().String()
+}
+
+// Compare returns -1, 0 or +1 depending on whether v has lower, equal or
+// higher precedence than v2. Prefix and build metadata are ignored.
+func (v Version) Compare(v2 Version) int {
+	return v.toInternal().Compare(v2.toInternal())
+}
+
+// Equal reports whether v and v2 are identical, including prefix and build
+// metadata
+func (v Version) Equal(v2 Version) bool {
+	return v.toInternal().Equal(v2.toInternal())
+}
+
+// Set implements flag.Value
+func (v *Version) Set(str string) error {
+	parsed, err := Parse(str)
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (v *Version) UnmarshalText(text []byte) error {
+	return v.Set(string(text))
+}
+
+// MarshalJSON implements json.Marshaler
+func (v Version) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (v *Version) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	return v.Set(str)
+}
+
+func (v Version) toInternal() internal.SemVer {
+	return internal.SemVer{
+		Prefix:        v.Prefix,
+		Major:         v.Major,
+		Minor:         v.Minor,
+		Patch:         v.Patch,
+		Prerelease:    v.Prerelease,
+		BuildMetadata: v.BuildMetadata,
+	}
+}
+
+func fromInternal(v internal.SemVer) Version {
+	return Version{
+		Prefix:        v.Prefix,
+		Major:         v.Major,
+		Minor:         v.Minor,
+		Patch:         v.Patch,
+		Prerelease:    v.Prerelease,
+		BuildMetadata: v.BuildMetadata,
+	}
+}
+
+// Constraint is a version range like ">=1.2.0 <2.0.0", "^1.4", "~1.2.3",
+// "1.x" or "1.2 - 1.4" with alternatives separated by "||"
+type Constraint struct {
+	c *internal.Constraint
+}
+
+// ParseConstraint parses a version range following the npm semantics
+func ParseConstraint(str string) (Constraint, error) {
+	c, err := internal.ParseConstraint(str)
+	if err != nil {
+		return Constraint{}, err
+	}
+	return Constraint{c: c}, nil
+}
+
+// Check reports whether the version satisfies the constraint. Prereleases
+// only satisfy a constraint if one of its comparators has a prerelease on the
+// same major, minor and patch version. The zero value matches everything.
+func (c Constraint) Check(v Version) bool {
+	if c.c == nil {
+		return true
+	}
+	return c.c.Check(v.toInternal())
+}
+
+// String implements fmt.Stringer and flag.Value
+func (c Constraint) String() string {
+	if c.c == nil {
+		return ""
+	}
+	return c.c.String()
+}
+
+// Set implements flag.Value
+func (c *Constraint) Set(str string) error {
+	parsed, err := ParseConstraint(str)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
@@ -0,0 +1,63 @@
+package semver
+
+import (
+	"encoding"
+	"encoding/json"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ encoding.TextMarshaler = Version{}
+	_ json.Marshaler         = Version{}
+	_ flag.Value             = &Version{}
+)
+
+func TestVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	v, err := Parse("v1.2.3-rc.1+build.5")
+	assert.NoError(err)
+	assert.Equal(Version{Prefix: "v", Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc", "1"}, BuildMetadata: []string{"build", "5"}}, v)
+	assert.Equal("v1.2.3-rc.1+build.5", v.String())
+	assert.Equal(-1, v.Compare(MustParse("1.2.3")))
+	assert.Equal(0, v.Compare(MustParse("1.2.3-rc.1")))
+	assert.False(v.Equal(MustParse("1.2.3-rc.1")))
+	_, err = Parse("1.2")
+	assert.Error(err)
+	assert.Panics(func() { MustParse("invalid") })
+
+	type document struct {
+		Version Version `json:"version"`
+	}
+	data, err := json.Marshal(document{Version: v})
+	assert.NoError(err)
+	assert.Equal(`{"version":"v1.2.3-rc.1+build.5"}`, string(data))
+	doc := document{}
+	assert.NoError(json.Unmarshal(data, &doc))
+	assert.Equal(v, doc.Version)
+	assert.Error(json.Unmarshal([]byte(`{"version":"1.2"}`), &doc))
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagged := Version{}
+	flags.Var(&flagged, "version", "")
+	assert.NoError(flags.Parse([]string{"-version", "2.0.0"}))
+	assert.Equal(MustParse("2.0.0"), flagged)
+}
+
+func TestConstraint(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := ParseConstraint(">=1.2.0 <2.0.0 || ^3.1")
+	assert.NoError(err)
+	assert.Equal(">=1.2.0 <2.0.0 || ^3.1", c.String())
+	assert.True(c.Check(MustParse("v1.2.0")))
+	assert.False(c.Check(MustParse("v2.0.0")))
+	assert.True(c.Check(MustParse("v3.5.0")))
+	assert.False(c.Check(MustParse("v1.5.0-rc.1")))
+	assert.True(Constraint{}.Check(MustParse("v1.5.0-rc.1")))
+	_, err = ParseConstraint(">=")
+	assert.Error(err)
+}